@@ -0,0 +1,151 @@
+package hash
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"reflect"
+)
+
+// Generic returns a hash function for any comparable type. The algorithm is
+// selected once, based on the kind of K:
+//
+//   - strings use [String];
+//   - integers use [Number];
+//   - floating point numbers use [Float];
+//   - booleans, pointers, channels, and arrays or structs made exclusively of
+//     these or of integers, without padding, use [Struct]. Blank fields, which
+//     can be used to make padding explicit, are assumed to always be zero;
+//   - all other types, e.g. structs with string fields, interfaces or complex
+//     numbers, are hashed field by field with [maphash]. This is much slower
+//     than the other cases and allocates.
+//
+// Named types are handled like their underlying type. The returned function is
+// safe for concurrent use.
+func Generic[K comparable]() func(K) uint64 {
+	t := reflect.TypeFor[K]()
+	switch t.Kind() {
+	case reflect.String:
+		return as[string, K](String())
+	case reflect.Int:
+		return as[int, K](Number[int]())
+	case reflect.Int8:
+		return as[int8, K](Number[int8]())
+	case reflect.Int16:
+		return as[int16, K](Number[int16]())
+	case reflect.Int32:
+		return as[int32, K](Number[int32]())
+	case reflect.Int64:
+		return as[int64, K](Number[int64]())
+	case reflect.Uint:
+		return as[uint, K](Number[uint]())
+	case reflect.Uint8:
+		return as[uint8, K](Number[uint8]())
+	case reflect.Uint16:
+		return as[uint16, K](Number[uint16]())
+	case reflect.Uint32:
+		return as[uint32, K](Number[uint32]())
+	case reflect.Uint64:
+		return as[uint64, K](Number[uint64]())
+	case reflect.Uintptr:
+		return as[uintptr, K](Number[uintptr]())
+	case reflect.Float32:
+		return as[float32, K](Float[float32]())
+	case reflect.Float64:
+		return as[float64, K](Float[float64]())
+	}
+	if memHashable(t, true) {
+		return Struct[K]()
+	}
+	seed := maphash.MakeSeed()
+	return func(key K) uint64 {
+		var h maphash.Hash
+		h.SetSeed(seed)
+		hashValue(&h, reflect.ValueOf(&key).Elem())
+		return h.Sum64()
+	}
+}
+
+// memHashable returns true if values of type t compare equal if and only if
+// their in-memory representations are identical. If ptrs is false, types
+// containing pointers or channels are rejected.
+func memHashable(t reflect.Type, ptrs bool) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	case reflect.Pointer, reflect.UnsafePointer, reflect.Chan:
+		return ptrs
+	case reflect.Array:
+		return memHashable(t.Elem(), ptrs)
+	case reflect.Struct:
+		var sz uintptr
+		for i := range t.NumField() {
+			f := t.Field(i)
+			// like in [Struct], blank fields are assumed to be always zero.
+			if !memHashable(f.Type, ptrs) {
+				return false
+			}
+			sz += f.Type.Size()
+		}
+		// padding
+		return sz == t.Size()
+	}
+	return false
+}
+
+// hashValue writes v to h such that values comparing equal produce the same
+// output.
+func hashValue(h *maphash.Hash, v reflect.Value) {
+	var b [8]byte
+	switch v.Kind() {
+	case reflect.String:
+		h.WriteString(v.String())
+	case reflect.Bool:
+		if v.Bool() {
+			h.WriteByte(1)
+		} else {
+			h.WriteByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		h.Write(binary.LittleEndian.AppendUint64(b[:0], uint64(v.Int())))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		h.Write(binary.LittleEndian.AppendUint64(b[:0], v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		hashFloat(h, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		hashFloat(h, real(c))
+		hashFloat(h, imag(c))
+	case reflect.Pointer, reflect.UnsafePointer, reflect.Chan:
+		h.Write(binary.LittleEndian.AppendUint64(b[:0], uint64(v.Pointer())))
+	case reflect.Interface:
+		if v.IsNil() {
+			h.WriteByte(0)
+			return
+		}
+		hashValue(h, v.Elem())
+	case reflect.Array:
+		for i := range v.Len() {
+			hashValue(h, v.Index(i))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := range v.NumField() {
+			if t.Field(i).Name != "_" {
+				hashValue(h, v.Field(i))
+			}
+		}
+	default:
+		// not comparable, == would panic anyway.
+		panic("hash: unhashable type " + v.Type().String())
+	}
+}
+
+func hashFloat(h *maphash.Hash, f float64) {
+	if f == 0 {
+		f = 0 // -0 => +0
+	}
+	var b [8]byte
+	h.Write(binary.LittleEndian.AppendUint64(b[:0], math.Float64bits(f)))
+}
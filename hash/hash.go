@@ -2,6 +2,7 @@ package hash
 
 import (
 	"hash/maphash"
+	"math"
 	"math/bits"
 	"math/rand/v2"
 	"unsafe"
@@ -31,9 +32,12 @@ type IntType interface {
 }
 
 func Number[T IntType]() func(v T) uint64 {
-	seed := rand.Uint64()
+	return number[T](rand.Uint64(), hashkey)
+}
+
+func number[T IntType](seed uint64, key [2]uint64) func(v T) uint64 {
 	var zero T
-	seed ^= mix(seed^hashkey[0], hashkey[1]) ^ uint64(unsafe.Sizeof(zero))
+	seed ^= mix(seed^key[0], key[1]) ^ uint64(unsafe.Sizeof(zero))
 	return func(v T) uint64 {
 		var a, b uint64
 		b = uint64(v)
@@ -43,8 +47,39 @@ func Number[T IntType]() func(v T) uint64 {
 		} else {
 			a = bits.RotateLeft64(b, 32)
 		}
-		b, a = bits.Mul64(a^hashkey[1], b^seed)
-		return mix(a^hashkey[0]^uint64(unsafe.Sizeof(v)), b^hashkey[1])
+		b, a = bits.Mul64(a^key[1], b^seed)
+		return mix(a^key[0]^uint64(unsafe.Sizeof(v)), b^key[1])
+	}
+}
+
+type FloatType interface {
+	~float32 | ~float64
+}
+
+// Float returns a hash function for floating point numbers. The hash is
+// computed from the IEEE 754 binary representation of the value, using the
+// same algorithm as [Number].
+//
+// Negative zero is normalized to +0 so that both hash to the same value.
+// NaN values hash consistently for a given bit pattern, but since NaN != NaN,
+// NaN keys can never be found in a hash table and should not be used.
+func Float[T FloatType]() func(T) uint64 {
+	var zero T
+	if unsafe.Sizeof(zero) == 4 {
+		h := Number[uint32]()
+		return func(v T) uint64 {
+			if v == 0 {
+				v = 0 // -0 => +0
+			}
+			return h(math.Float32bits(float32(v)))
+		}
+	}
+	h := Number[uint64]()
+	return func(v T) uint64 {
+		if v == 0 {
+			v = 0 // -0 => +0
+		}
+		return h(math.Float64bits(float64(v)))
 	}
 }
 
@@ -53,12 +88,22 @@ func mix(a, b uint64) uint64 {
 	return hi ^ lo
 }
 
-func Generic[K comparable]() func(K) uint64 {
-	var h maphash.Hash
-	h.SetSeed(maphash.MakeSeed())
+// Struct returns a hash function for small fixed size keys like structs or
+// arrays. It hashes the in-memory representation of keys with the same
+// algorithm as [StringSeeded] and a random seed. [Generic] uses the same
+// algorithm for keys that it can prove are safe to hash byte-wise.
+//
+// Since keys are hashed byte-wise, two keys comparing equal must have the same
+// in-memory representation, which is only the case if K is exclusively made of
+// integers, booleans, pointers, channels, or arrays/structs of these. In
+// particular, K must not contain strings, interfaces or floating point numbers.
+// K should also not contain any padding since the contents of padding bytes
+// are undefined: for example, struct{ a int64; b uint32 } has 4 trailing
+// padding bytes on 64 bits platforms, use struct{ a int64; b, _ uint32 }
+// instead.
+func Struct[K comparable]() func(K) uint64 {
+	seed := rand.Uint64()
 	return func(key K) uint64 {
-		h.Reset()
-		h.Write(unsafe.Slice((*byte)(unsafe.Pointer(&key)), unsafe.Sizeof(key)))
-		return h.Sum64()
+		return hashBytes(unsafe.Slice((*byte)(unsafe.Pointer(&key)), unsafe.Sizeof(key)), seed)
 	}
 }
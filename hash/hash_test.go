@@ -0,0 +1,282 @@
+package hash_test
+
+import (
+	"fmt"
+	"hash/maphash"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/db47h/cache/v2/hash"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFloat(t *testing.T) {
+	h64 := hash.Float[float64]()
+	negZero := math.Copysign(0, -1)
+	require.Equal(t, h64(0), h64(negZero))
+	require.NotEqual(t, h64(1), h64(-1))
+	require.NotEqual(t, h64(1), h64(1+1e-15))
+	nan := math.NaN()
+	require.Equal(t, h64(nan), h64(nan))
+
+	h32 := hash.Float[float32]()
+	require.Equal(t, h32(0), h32(float32(negZero)))
+	require.NotEqual(t, h32(1), h32(-1))
+	require.Equal(t, h32(float32(nan)), h32(float32(nan)))
+
+	type celsius float32
+	hc := hash.Float[celsius]()
+	require.Equal(t, hc(-12.5), hc(-12.5))
+}
+
+func TestSeeded(t *testing.T) {
+	const n = 100000
+	s1, s2 := hash.StringSeeded(42), hash.StringSeeded(42)
+	b1 := hash.BytesSeeded(42)
+	o := hash.StringSeeded(43)
+	seen := make(map[uint64]struct{}, n)
+	var buf []byte
+	for i := range n {
+		// cover all length classes
+		buf = strconv.AppendInt(buf[:0], int64(i), 10)
+		buf = append(buf, strings.Repeat("x", i%40)...)
+		s := string(buf)
+		h := s1(s)
+		require.Equal(t, h, s2(s))
+		require.Equal(t, h, b1(buf))
+		require.NotEqual(t, h, o(s))
+		_, dup := seen[h]
+		require.False(t, dup, "collision for %q", s)
+		seen[h] = struct{}{}
+	}
+	require.NotEqual(t, s1(""), o(""))
+
+	n1, n2 := hash.NumberSeeded[int](42), hash.NumberSeeded[int](42)
+	for i := range 1000 {
+		require.Equal(t, n1(i), n2(i))
+	}
+	require.NotEqual(t, n1(1), hash.NumberSeeded[int](43)(1))
+
+	// seeded hashes must not change across runs or releases.
+	require.Equal(t, uint64(0x7f36b44045d68ab0), hash.StringSeeded(0)("hello"))
+	require.Equal(t, uint64(0xd9bc1542c0cc68e8), hash.StringSeeded(0)("hello, world and everyone else"))
+	require.Equal(t, uint64(0x6a75cdd858ef9f03), hash.NumberSeeded[uint64](0)(42))
+}
+
+func TestGenericSeeded(t *testing.T) {
+	type name string
+	type id uint16
+	s := strings.Repeat("hello", 3)
+	require.Equal(t, hash.StringSeeded(42)("hello"), hash.GenericSeeded[string](42)(s[5:10]))
+	require.Equal(t, hash.StringSeeded(42)("hello"), hash.GenericSeeded[name](42)("hello"))
+	require.Equal(t, hash.NumberSeeded[uint16](42)(7), hash.GenericSeeded[id](42)(7))
+	require.Equal(t, hash.NumberSeeded[int](42)(-1), hash.GenericSeeded[int](42)(-1))
+	f := hash.GenericSeeded[float64](42)
+	require.Equal(t, f(0), f(math.Copysign(0, -1)))
+	require.NotEqual(t, f(1), f(2))
+	k := hash.GenericSeeded[structKey](42)
+	require.Equal(t, k(structKey{UserID: 1, Region: 2}), hash.GenericSeeded[structKey](42)(structKey{UserID: 1, Region: 2}))
+	require.NotEqual(t, k(structKey{UserID: 1, Region: 2}), hash.GenericSeeded[structKey](43)(structKey{UserID: 1, Region: 2}))
+
+	// field by field
+	type mixed struct {
+		Name string
+		ID   int
+	}
+	hm := hash.GenericSeeded[mixed](42)
+	require.Equal(t, hm(mixed{s[5:10], 1}), hm(mixed{"hello", 1}))
+	require.NotEqual(t, hm(mixed{"hello", 1}), hm(mixed{"hello", 2}))
+	require.Equal(t, hm(mixed{"hello", 1}), hash.GenericSeeded[mixed](42)(mixed{"hello", 1}))
+	type padded struct {
+		A int64
+		B uint8
+	}
+	hp := hash.GenericSeeded[padded](42)
+	require.Equal(t, hp(padded{1, 2}), hp(padded{1, 2}))
+	hi := hash.GenericSeeded[any](42)
+	require.Equal(t, hi(s[5:10]), hi("hello"))
+	require.Equal(t, hi(nil), hi(nil))
+	require.NotEqual(t, hi(1), hi(2))
+	require.Panics(t, func() { hi(new(int)) })
+
+	// pointers are not reproducible
+	require.Panics(t, func() { hash.GenericSeeded[*int](42) })
+	require.Panics(t, func() {
+		hash.GenericSeeded[struct {
+			A int
+			P chan int
+		}](42)
+	})
+}
+
+func TestGeneric(t *testing.T) {
+	// equal strings with different backing arrays
+	s := strings.Repeat("hello", 3)
+	hs := hash.Generic[string]()
+	require.Equal(t, hs("hello"), hs(s[5:10]))
+	require.NotEqual(t, hs("hello"), hs("world"))
+
+	f := hash.Generic[float32]()
+	require.Equal(t, f(0), f(float32(math.Copysign(0, -1))))
+
+	type mixed struct {
+		Name string
+		ID   int
+	}
+	hm := hash.Generic[mixed]()
+	require.Equal(t, hm(mixed{s[5:10], 1}), hm(mixed{"hello", 1}))
+	require.NotEqual(t, hm(mixed{"hello", 1}), hm(mixed{"hello", 2}))
+
+	hi := hash.Generic[any]()
+	require.Equal(t, hi(s[5:10]), hi("hello"))
+	require.Equal(t, hi(nil), hi(nil))
+	require.NotEqual(t, hi(1), hi(2))
+
+	// padding and blank fields are not hashed
+	type padded struct {
+		A int64
+		B uint8
+	}
+	hp := hash.Generic[padded]()
+	require.Equal(t, hp(padded{1, 2}), hp(padded{1, 2}))
+	hk := hash.Generic[structKey]()
+	require.Equal(t, hk(structKey{UserID: 1, Region: 2}), hk(structKey{UserID: 1, Region: 2}))
+
+	ha := hash.Generic[[16]byte]()
+	require.Equal(t, ha([16]byte{1, 2, 3}), ha([16]byte{1, 2, 3}))
+	require.NotEqual(t, ha([16]byte{1, 2, 3}), ha([16]byte{3, 2, 1}))
+}
+
+func TestGeneric_concurrent(t *testing.T) {
+	h := hash.Generic[string]()
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				k := strconv.Itoa(g*1000 + i)
+				if h(k) != h(strings.Clone(k)) {
+					t.Errorf("inconsistent hash for %q", k)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+type structKey struct {
+	UserID int64
+	Region uint32
+	_      uint32
+}
+
+func TestStruct(t *testing.T) {
+	h := hash.Struct[structKey]()
+	seen := make(map[uint64]struct{})
+	for id := range int64(100) {
+		for r := range uint32(100) {
+			k := structKey{UserID: id, Region: r}
+			v := h(k)
+			require.Equal(t, v, h(structKey{UserID: id, Region: r}))
+			_, dup := seen[v]
+			require.False(t, dup)
+			seen[v] = struct{}{}
+		}
+	}
+	ha := hash.Struct[[3]byte]()
+	require.Equal(t, ha([3]byte{1, 2, 3}), ha([3]byte{1, 2, 3}))
+	require.NotEqual(t, ha([3]byte{1, 2, 3}), ha([3]byte{3, 2, 1}))
+}
+
+var sink uint64
+
+func Benchmark_Struct(b *testing.B) {
+	b.Run("Struct", func(b *testing.B) {
+		h := hash.Struct[structKey]()
+		for i := range b.N {
+			sink += h(structKey{UserID: int64(i), Region: uint32(i)})
+		}
+	})
+	b.Run("Generic", func(b *testing.B) {
+		h := hash.Generic[structKey]()
+		for i := range b.N {
+			sink += h(structKey{UserID: int64(i), Region: uint32(i)})
+		}
+	})
+}
+
+func Benchmark_Generic(b *testing.B) {
+	type pair struct {
+		A int64
+		B uint64
+	}
+	seed := maphash.MakeSeed()
+	b.Run("[16]byte", func(b *testing.B) {
+		h := hash.Generic[[16]byte]()
+		var k [16]byte
+		for i := range b.N {
+			k[0] = byte(i)
+			sink += h(k)
+		}
+	})
+	b.Run("[16]byte/maphash", func(b *testing.B) {
+		var k [16]byte
+		for i := range b.N {
+			k[0] = byte(i)
+			sink += maphash.Bytes(seed, k[:])
+		}
+	})
+	b.Run("struct", func(b *testing.B) {
+		h := hash.Generic[pair]()
+		for i := range b.N {
+			sink += h(pair{int64(i), uint64(i)})
+		}
+	})
+	b.Run("struct/maphash", func(b *testing.B) {
+		for i := range b.N {
+			k := pair{int64(i), uint64(i)}
+			sink += maphash.Bytes(seed, unsafe.Slice((*byte)(unsafe.Pointer(&k)), unsafe.Sizeof(k)))
+		}
+	})
+}
+
+func TestXXH64(t *testing.T) {
+	h := hash.XXH64()
+	for _, tt := range []struct {
+		in   string
+		want uint64
+	}{
+		{"", 0xef46db3751d8e999},
+		{"a", 0xd24ec4f1a98c6e5b},
+		{"as", 0x1c330fb2d66be179},
+		{"asd", 0x631c37ce72a97393},
+		{"asdf", 0x415872f599cea71e},
+		{"Call me Ishmael. Some years ago--never mind how long precisely-", 0x02a2e85470d6fd96},
+	} {
+		require.Equal(t, tt.want, h([]byte(tt.in)), "%q", tt.in)
+	}
+}
+
+func Benchmark_XXH64(b *testing.B) {
+	for _, n := range []int{8, 16, 64} {
+		data := make([]byte, n)
+		b.Run(fmt.Sprintf("XXH64_%d", n), func(b *testing.B) {
+			h := hash.XXH64()
+			for range b.N {
+				sink += h(data)
+			}
+		})
+		b.Run(fmt.Sprintf("Bytes_%d", n), func(b *testing.B) {
+			h := hash.Bytes()
+			for range b.N {
+				sink += h(data)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package hash
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"reflect"
+	"unsafe"
+)
+
+// Seeded hash functions do not use any per-process random state: for a given
+// seed, they always return the same hash values, which makes them suitable for
+// reproducible hash table layouts across runs.
+
+// default rapidhash secrets
+var secret = [...]uint64{0x2d358dccaa6c78a5, 0x8bb84b93962eacc9, 0x4b33a62ed433d4a3}
+
+// StringSeeded returns a deterministic hash function for strings using the
+// given seed.
+func StringSeeded(seed uint64) func(string) uint64 {
+	return func(s string) uint64 {
+		return hashBytes(unsafe.Slice(unsafe.StringData(s), len(s)), seed)
+	}
+}
+
+// BytesSeeded returns a deterministic hash function for byte slices using the
+// given seed.
+func BytesSeeded(seed uint64) func([]byte) uint64 {
+	return func(b []byte) uint64 {
+		return hashBytes(b, seed)
+	}
+}
+
+// NumberSeeded is like [Number] but returns a deterministic hash function
+// using the given seed.
+func NumberSeeded[T IntType](seed uint64) func(T) uint64 {
+	return number[T](seed, [2]uint64{secret[0], secret[1]})
+}
+
+// GenericSeeded returns a deterministic hash function for any comparable type
+// using the given seed. Strings, integers and floating point numbers, including
+// named types based on these, use the same algorithm as [StringSeeded],
+// [NumberSeeded] and [Float] respectively. Booleans, and arrays or structs made
+// exclusively of these or of integers, without padding, are hashed byte-wise
+// like with [Struct]. All other types, e.g. structs with string fields or
+// interfaces, are hashed field by field, which is much slower and allocates.
+//
+// Since pointer values change from one run to the next, GenericSeeded panics
+// if K contains pointers or channels, and the returned function panics if an
+// interface key holds such a value.
+func GenericSeeded[K comparable](seed uint64) func(K) uint64 {
+	t := reflect.TypeFor[K]()
+	switch t.Kind() {
+	case reflect.String:
+		return as[string, K](StringSeeded(seed))
+	case reflect.Int:
+		return as[int, K](NumberSeeded[int](seed))
+	case reflect.Int8:
+		return as[int8, K](NumberSeeded[int8](seed))
+	case reflect.Int16:
+		return as[int16, K](NumberSeeded[int16](seed))
+	case reflect.Int32:
+		return as[int32, K](NumberSeeded[int32](seed))
+	case reflect.Int64:
+		return as[int64, K](NumberSeeded[int64](seed))
+	case reflect.Uint:
+		return as[uint, K](NumberSeeded[uint](seed))
+	case reflect.Uint8:
+		return as[uint8, K](NumberSeeded[uint8](seed))
+	case reflect.Uint16:
+		return as[uint16, K](NumberSeeded[uint16](seed))
+	case reflect.Uint32:
+		return as[uint32, K](NumberSeeded[uint32](seed))
+	case reflect.Uint64:
+		return as[uint64, K](NumberSeeded[uint64](seed))
+	case reflect.Uintptr:
+		return as[uintptr, K](NumberSeeded[uintptr](seed))
+	case reflect.Float32:
+		h := NumberSeeded[uint32](seed)
+		return as[float32, K](func(v float32) uint64 {
+			if v == 0 {
+				v = 0 // -0 => +0
+			}
+			return h(math.Float32bits(v))
+		})
+	case reflect.Float64:
+		h := NumberSeeded[uint64](seed)
+		return as[float64, K](func(v float64) uint64 {
+			if v == 0 {
+				v = 0 // -0 => +0
+			}
+			return h(math.Float64bits(v))
+		})
+	}
+	if memHashable(t, false) {
+		return func(key K) uint64 {
+			return hashBytes(unsafe.Slice((*byte)(unsafe.Pointer(&key)), unsafe.Sizeof(key)), seed)
+		}
+	}
+	if hasPointers(t) {
+		panic("hash: GenericSeeded: type " + t.String() + " contains pointers")
+	}
+	return func(key K) uint64 {
+		return hashBytes(appendValue(nil, reflect.ValueOf(&key).Elem()), seed)
+	}
+}
+
+// hasPointers returns true if values of type t may contain pointers or
+// channels, not counting the dynamic values of interfaces.
+func hasPointers(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.UnsafePointer, reflect.Chan:
+		return true
+	case reflect.Array:
+		return hasPointers(t.Elem())
+	case reflect.Struct:
+		for i := range t.NumField() {
+			if hasPointers(t.Field(i).Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// appendValue appends a representation of v to b such that values comparing
+// equal produce the same output. It is the seeded counterpart of hashValue.
+func appendValue(b []byte, v reflect.Value) []byte {
+	switch v.Kind() {
+	case reflect.String:
+		s := v.String()
+		b = binary.LittleEndian.AppendUint64(b, uint64(len(s)))
+		return append(b, s...)
+	case reflect.Bool:
+		if v.Bool() {
+			return append(b, 1)
+		}
+		return append(b, 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return binary.LittleEndian.AppendUint64(b, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return binary.LittleEndian.AppendUint64(b, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return appendFloat(b, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return appendFloat(appendFloat(b, real(c)), imag(c))
+	case reflect.Interface:
+		if v.IsNil() {
+			return append(b, 0)
+		}
+		return appendValue(append(b, 1), v.Elem())
+	case reflect.Array:
+		for i := range v.Len() {
+			b = appendValue(b, v.Index(i))
+		}
+		return b
+	case reflect.Struct:
+		t := v.Type()
+		for i := range v.NumField() {
+			if t.Field(i).Name != "_" {
+				b = appendValue(b, v.Field(i))
+			}
+		}
+		return b
+	}
+	panic("hash: GenericSeeded: cannot hash value of type " + v.Type().String())
+}
+
+func appendFloat(b []byte, f float64) []byte {
+	if f == 0 {
+		f = 0 // -0 => +0
+	}
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(f))
+}
+
+// as converts a hash function for type T to a hash function for type K, where
+// T is the underlying type of K.
+func as[T any, K any](h func(T) uint64) func(K) uint64 {
+	return func(key K) uint64 {
+		return h(*(*T)(unsafe.Pointer(&key)))
+	}
+}
+
+// hashBytes is a simplified, single lane, version of rapidhash.
+func hashBytes(p []byte, seed uint64) uint64 {
+	n := len(p)
+	seed ^= mix(seed^secret[0], secret[1]) ^ uint64(n)
+	var a, b uint64
+	if n <= 16 {
+		if n >= 4 {
+			last := n - 4
+			a = uint64(r4(p))<<32 | uint64(r4(p[last:]))
+			delta := (n & 24) >> (n >> 3)
+			b = uint64(r4(p[delta:]))<<32 | uint64(r4(p[last-delta:]))
+		} else if n > 0 {
+			a = uint64(p[0])<<56 | uint64(p[n>>1])<<32 | uint64(p[n-1])
+		}
+	} else {
+		i := 0
+		for ; n-i > 16; i += 16 {
+			seed = mix(r8(p[i:])^secret[2], r8(p[i+8:])^seed)
+		}
+		a = r8(p[n-16:])
+		b = r8(p[n-8:])
+	}
+	b, a = bits.Mul64(a^secret[1], b^seed)
+	return mix(a^secret[0]^uint64(n), b^secret[1])
+}
+
+func r4(p []byte) uint32 { return binary.LittleEndian.Uint32(p) }
+func r8(p []byte) uint64 { return binary.LittleEndian.Uint64(p) }
@@ -0,0 +1,82 @@
+package hash
+
+import "math/bits"
+
+const (
+	prime64_1 = 11400714785074694791
+	prime64_2 = 14029467366897019727
+	prime64_3 = 1609587929392839161
+	prime64_4 = 9650029242287828579
+	prime64_5 = 2870177450012600261
+)
+
+// XXH64 returns a hash function for byte slices implementing xxHash64 with a
+// seed of 0. It is compatible with other xxHash64 implementations, which makes
+// it possible to use hashes computed elsewhere.
+//
+// Unlike [Bytes], the hash values are the same in every run, so it should not
+// be used with untrusted keys.
+func XXH64() func([]byte) uint64 {
+	return func(b []byte) uint64 {
+		return xxh64(b, 0)
+	}
+}
+
+func xxh64(b []byte, seed uint64) uint64 {
+	n := len(b)
+	var h uint64
+	if n >= 32 {
+		v1 := seed + prime64_1 + prime64_2
+		v2 := seed + prime64_2
+		v3 := seed
+		v4 := seed - prime64_1
+		for len(b) >= 32 {
+			v1 = xxhRound(v1, r8(b))
+			v2 = xxhRound(v2, r8(b[8:]))
+			v3 = xxhRound(v3, r8(b[16:]))
+			v4 = xxhRound(v4, r8(b[24:]))
+			b = b[32:]
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) + bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxhMerge(h, v1)
+		h = xxhMerge(h, v2)
+		h = xxhMerge(h, v3)
+		h = xxhMerge(h, v4)
+	} else {
+		h = seed + prime64_5
+	}
+	h += uint64(n)
+
+	for ; len(b) >= 8; b = b[8:] {
+		h ^= xxhRound(0, r8(b))
+		h = bits.RotateLeft64(h, 27)*prime64_1 + prime64_4
+	}
+	if len(b) >= 4 {
+		h ^= uint64(r4(b)) * prime64_1
+		h = bits.RotateLeft64(h, 23)*prime64_2 + prime64_3
+		b = b[4:]
+	}
+	for _, c := range b {
+		h ^= uint64(c) * prime64_5
+		h = bits.RotateLeft64(h, 11) * prime64_1
+	}
+
+	h ^= h >> 33
+	h *= prime64_2
+	h ^= h >> 29
+	h *= prime64_3
+	h ^= h >> 32
+	return h
+}
+
+func xxhRound(acc, input uint64) uint64 {
+	acc += input * prime64_2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * prime64_1
+}
+
+func xxhMerge(acc, val uint64) uint64 {
+	val = xxhRound(0, val)
+	acc ^= val
+	return acc*prime64_1 + prime64_4
+}
@@ -0,0 +1,96 @@
+package lru
+
+import (
+	"unsafe"
+
+	"github.com/db47h/cache/v2/hash"
+)
+
+// ByteMap is a [Map] with []byte keys. It avoids the allocation incurred by
+// converting []byte keys to strings when looking up entries: keys are only
+// copied when a new entry is inserted, so callers are free to reuse their key
+// buffers.
+//
+// The default hasher is [hash.String].
+type ByteMap[V any] struct {
+	m Map[string, V]
+}
+
+// NewByteMap returns a new ByteMap initialized with the given options. See
+// [NewMap].
+func NewByteMap[V any](opts ...Option) *ByteMap[V] {
+	var m ByteMap[V]
+	m.Init(opts...)
+	return &m
+}
+
+// Init initializes or clears m with the given options. Unless [WithHasher] or
+// [WithHasherSeed] is given, keys are hashed with [hash.String].
+func (m *ByteMap[V]) Init(opts ...Option) {
+	var o options
+	for _, op := range opts {
+		op.set(&o)
+	}
+	if o.hasher == nil && o.seed == nil {
+		opts = append([]Option{WithHasher(hash.String())}, opts...)
+	}
+	m.m.Init(opts...)
+}
+
+func (m *ByteMap[V]) init() {
+	if m.m.capacity == 0 {
+		m.Init()
+	}
+}
+
+// str returns key as a string without copying. The result must not be retained.
+func str(key []byte) string {
+	return unsafe.String(unsafe.SliceData(key), len(key))
+}
+
+// Set sets the value for the given key. See [Map.Set].
+func (m *ByteMap[V]) Set(key []byte, value V) (prev V, replaced bool) {
+	m.init()
+	k := str(key)
+	hash, i := m.m.find(k)
+	if i == 0 || m.m.elms[i].expired() {
+		// new entry
+		k = string(key)
+	} else {
+		// do not leak the caller's buffer to onRemove
+		k = m.m.elms[i].key
+	}
+	return m.m.set(hash, i, k, value, expiry(m.m.ttl))
+}
+
+// Get returns the value for the given key. See [Map.Get].
+func (m *ByteMap[V]) Get(key []byte) (V, bool) {
+	m.init()
+	return m.m.Get(str(key))
+}
+
+// Peek returns the value for the given key without updating its recency. See
+// [Map.Peek].
+func (m *ByteMap[V]) Peek(key []byte) (V, bool) {
+	m.init()
+	return m.m.Peek(str(key))
+}
+
+// Delete deletes the given key. See [Map.Delete].
+func (m *ByteMap[V]) Delete(key []byte) (V, bool) {
+	m.init()
+	return m.m.Delete(str(key))
+}
+
+// DeleteLRU evicts the least recently used entry. See [Map.DeleteLRU].
+func (m *ByteMap[V]) DeleteLRU() (key string, value V) {
+	return m.m.DeleteLRU()
+}
+
+// All returns an iterator for all key value pairs in the Map, lru first.
+func (m *ByteMap[V]) All() func(yield func(string, V) bool) {
+	return m.m.All()
+}
+
+// Len returns the number of entries in the ByteMap. See [Map.Len].
+func (m *ByteMap[V]) Len() int { return m.m.Len() }
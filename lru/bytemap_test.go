@@ -0,0 +1,101 @@
+package lru_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/db47h/cache/v2/hash"
+	"github.com/db47h/cache/v2/lru"
+	"github.com/stretchr/testify/require"
+)
+
+func TestByteMap(t *testing.T) {
+	var m lru.ByteMap[int]
+	var buf []byte
+	for i := range 1000 {
+		buf = strconv.AppendInt(buf[:0], int64(i), 10)
+		m.Set(buf, i)
+	}
+	require.Equal(t, 1000, m.Len())
+	// keys must have been copied
+	i := 0
+	for k, v := range m.All() {
+		require.Equal(t, strconv.Itoa(i), k)
+		require.Equal(t, i, v)
+		i++
+	}
+	for i := range 1000 {
+		buf = strconv.AppendInt(buf[:0], int64(i), 10)
+		v, ok := m.Get(buf)
+		require.True(t, ok)
+		require.Equal(t, i, v)
+	}
+	_, replaced := m.Set([]byte("42"), -42)
+	require.True(t, replaced)
+	v, ok := m.Delete([]byte("42"))
+	require.True(t, ok)
+	require.Equal(t, -42, v)
+	_, ok = m.Peek([]byte("42"))
+	require.False(t, ok)
+
+	buf = []byte("12")
+	allocs := testing.AllocsPerRun(100, func() {
+		m.Get(buf)
+		m.Set(buf, 12)
+	})
+	require.Zero(t, allocs)
+}
+
+func Benchmark_ByteMap_Get(b *testing.B) {
+	keys := make([][]byte, 1000)
+	for i := range keys {
+		keys[i] = strconv.AppendInt(nil, int64(i)*7919, 10)
+	}
+	b.Run("ByteMap", func(b *testing.B) {
+		b.ReportAllocs()
+		m := lru.NewByteMap[int]()
+		for i, k := range keys {
+			m.Set(k, i)
+		}
+		b.ResetTimer()
+		for i := range b.N {
+			m.Get(keys[i%len(keys)])
+		}
+	})
+	b.Run("Map", func(b *testing.B) {
+		b.ReportAllocs()
+		m := lru.NewMap[string, int](lru.WithHasher(hash.String()))
+		for i, k := range keys {
+			m.Set(string(k), i)
+		}
+		b.ResetTimer()
+		for i := range b.N {
+			m.Get(string(keys[i%len(keys)]))
+		}
+	})
+	b.Run("builtin", func(b *testing.B) {
+		b.ReportAllocs()
+		m := make(map[string]int, len(keys))
+		for i, k := range keys {
+			m[string(k)] = i
+		}
+		b.ResetTimer()
+		for i := range b.N {
+			_ = m[string(keys[i%len(keys)])]
+		}
+	})
+}
+
+func TestByteMap_options(t *testing.T) {
+	require.NotPanics(t, func() { lru.NewByteMap[int](lru.WithHasherSeed(42)) })
+
+	var removed []string
+	m := lru.NewByteMap[int](lru.WithOnEvictReason(func(k string, _ int, _ lru.EvictReason) {
+		removed = append(removed, k)
+	}))
+	buf := []byte("key")
+	m.Set(buf, 1)
+	m.Set(buf, 2)
+	copy(buf, "xxx")
+	require.Equal(t, []string{"key"}, removed)
+}
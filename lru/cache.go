@@ -0,0 +1,268 @@
+package lru
+
+import (
+	"slices"
+	"time"
+)
+
+// Cache is a [Map] bounded to a maximum number of entries: when a new entry
+// pushes the number of entries above that maximum, the least recently used
+// entry is evicted, and the eviction callback, if any, is called.
+//
+// The Cache can also be bounded by the total size of its entries with the
+// [WithSizer] and [WithMaxSize] options, in which case as many entries as
+// necessary are evicted to make room for new or updated ones.
+//
+// The underlying table is sized once so that it never needs to grow, whatever
+// the number of entries evicted and inserted.
+//
+// A Cache can also be unbounded in number of entries, in which case the
+// underlying table grows as needed like a Map's and entries are only evicted
+// when exceeding the maximum size, if any, or explicitly with
+// [Cache.DeleteLRU] or [Cache.EvictToSize].
+type Cache[K comparable, V any] struct {
+	m       Map[K, V]
+	maxLen  int
+	maxSize int64
+}
+
+// New returns a new Cache holding at most maxLen entries. A maxLen <= 0 means
+// no limit on the number of entries. The capacity set with [WithCapacity] is
+// only used as a minimum.
+func New[K comparable, V any](maxLen int, opts ...Option) *Cache[K, V] {
+	maxLen = max(maxLen, 0)
+	o := getOpts[K](opts)
+	c := &Cache[K, V]{maxLen: maxLen, maxSize: o.maxSize}
+	if maxLen == 0 {
+		c.m.Init(opts...)
+		return c
+	}
+	c.m.Init(append(slices.Clip(opts), WithCapacity(cacheCapacity(maxLen, o.capacity, o.maxLoad)))...)
+	return c
+}
+
+// cacheCapacity returns the smallest table capacity >= min for which a table
+// holding maxLen entries is rehashed in place rather than grown when running
+// out of free slots. See Map.rehashOrGrow.
+func cacheCapacity(maxLen, min int, maxLoad float64) int {
+	c := roundSizeUp(min)
+	for maxLen > rehashInPlaceMax(maxActive(c, maxLoad)) {
+		c <<= 1
+	}
+	return c
+}
+
+// Set sets the value for the given key and evicts the least recently used
+// entry if the Cache is full. See [Map.Set].
+//
+// If a maximum size is set and value is larger than that maximum, the Cache is
+// left unchanged and Set returns the zero value of V and false. In particular,
+// no entries are evicted. The same applies when pinned entries leave no room for
+// the value, that is when inserting a new key in a full Cache where all entries
+// are pinned, or when the value and the pinned entries would exceed the maximum
+// size. See [Cache.Pin]. Use [Cache.TrySet] to tell these cases from the
+// insertion of a new key.
+func (c *Cache[K, V]) Set(key K, value V) (prev V, replaced bool) {
+	prev, replaced, _, _, _ = c.set(key, value, expiry(c.m.ttl))
+	return prev, replaced
+}
+
+// TrySet is like [Cache.Set] but also returns false if value was rejected,
+// either because it is too large or because pinned entries leave no room for
+// it.
+func (c *Cache[K, V]) TrySet(key K, value V) (prev V, replaced bool, ok bool) {
+	prev, replaced, _, _, ok = c.set(key, value, expiry(c.m.ttl))
+	return prev, replaced, ok
+}
+
+// SetReport is like [Cache.TrySet] but also reports whether inserting the
+// entry caused an eviction. If so, evictedKey is the key of the first entry
+// evicted, that is the least recently used one when SetReport was called. When
+// bounded by size, a single call may evict more entries.
+func (c *Cache[K, V]) SetReport(key K, value V) (prev V, replaced bool, evictedKey K, evicted bool, ok bool) {
+	return c.set(key, value, expiry(c.m.ttl))
+}
+
+// SetWithTTL is like [Cache.Set] but sets a specific time to live for the
+// entry. See [Map.SetWithTTL].
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) (prev V, replaced bool) {
+	prev, replaced, _, _, _ = c.set(key, value, expiry(ttl))
+	return prev, replaced
+}
+
+func (c *Cache[K, V]) set(key K, value V, expires int64) (prev V, replaced bool, evictedKey K, evicted bool, ok bool) {
+	if c.tooLarge(value) {
+		return
+	}
+	hash, i := c.m.find(key)
+	if !c.fits(i, value) {
+		return
+	}
+	prev, replaced = c.m.set(hash, i, key, value, expires)
+	var n int
+	n, evictedKey = c.evict()
+	return prev, replaced, evictedKey, n > 0, true
+}
+
+// UpdateSize updates the total size of the Cache after the value for the given
+// key has been updated in place, then evicts least recently used entries as
+// needed to honor the maximum size. See [Map.UpdateSize].
+//
+// If the updated value alone is larger than the maximum size, it is evicted
+// rather than all other entries, unless it is pinned. UpdateSize returns false
+// if the key was not found or if its entry was evicted.
+func (c *Cache[K, V]) UpdateSize(key K, oldSize int64) bool {
+	_, i := c.m.find(key)
+	if i == 0 {
+		return false
+	}
+	c.m.updateSize(i, oldSize)
+	if it := &c.m.elms[i]; !it.pinned && c.tooLarge(it.value) {
+		c.m.evict(i)
+		return false
+	}
+	c.evict()
+	_, i = c.m.find(key)
+	return i != 0
+}
+
+// fits returns true if value, set in slot i or in a new slot if i is 0, would
+// not be evicted right away because pinned entries take all the room.
+func (c *Cache[K, V]) fits(i int, value V) bool {
+	if i == 0 && c.maxLen > 0 && c.m.pinned >= c.maxLen {
+		return false
+	}
+	if c.maxSize <= 0 || c.m.sizer == nil || c.m.pinned == 0 {
+		return true
+	}
+	size := c.m.sizer(value)
+	for j := c.m.lru(); j != 0; j = c.m.elms[j].prev {
+		if it := &c.m.elms[j]; it.pinned && j != i {
+			size += c.m.sizer(it.value)
+		}
+	}
+	return size <= c.maxSize
+}
+
+// tooLarge returns true if value can never fit in the Cache.
+func (c *Cache[K, V]) tooLarge(value V) bool {
+	return c.maxSize > 0 && c.m.sizer != nil && c.m.sizer(value) > c.maxSize
+}
+
+// evict evicts entries until the Cache holds at most maxLen entries and, if
+// a maximum size is set, until Size() <= maxSize, or until all remaining
+// entries are pinned. It returns the number of entries evicted and the key of
+// the first one.
+func (c *Cache[K, V]) evict() (n int, first K) {
+	for (c.maxLen > 0 && c.m.active > c.maxLen || c.maxSize > 0 && c.m.size > c.maxSize) && c.m.active > c.m.pinned {
+		k, _ := c.m.DeleteLRU()
+		if n == 0 {
+			first = k
+		}
+		n++
+	}
+	return n, first
+}
+
+// SetMaxLen sets the maximum number of entries in the Cache, evicting least
+// recently used entries as needed, and returns the number of entries evicted.
+// A maxLen <= 0 means no limit. Unless unbounded, the underlying table is
+// resized to fit the new maximum.
+func (c *Cache[K, V]) SetMaxLen(maxLen int) int {
+	c.maxLen = max(maxLen, 0)
+	n, _ := c.evict()
+	if c.maxLen > 0 {
+		c.m.Resize(cacheCapacity(c.maxLen, minCapacity, c.m.maxLoad))
+	}
+	return n
+}
+
+// SetMaxSize sets the maximum total size of the entries in the Cache, evicting
+// least recently used entries as needed, and returns the number of entries
+// evicted. A value <= 0 means no limit.
+func (c *Cache[K, V]) SetMaxSize(max int64) int {
+	c.maxSize = max
+	n, _ := c.evict()
+	return n
+}
+
+// EvictToSize evicts least recently used entries until Size() <= max and
+// returns the number of entries evicted. See [Map.EvictToSize].
+func (c *Cache[K, V]) EvictToSize(max int64) int { return c.m.EvictToSize(max) }
+
+// Get returns the value for the given key. See [Map.Get].
+func (c *Cache[K, V]) Get(key K) (V, bool) { return c.m.Get(key) }
+
+// GetFresh returns the value for the given key and whether it was found,
+// missing or expired. See [Map.GetFresh].
+func (c *Cache[K, V]) GetFresh(key K) (V, LookupState) { return c.m.GetFresh(key) }
+
+// Touch marks the entry for the given key as the most recently used. See
+// [Map.Touch].
+func (c *Cache[K, V]) Touch(key K) bool { return c.m.Touch(key) }
+
+// Peek returns the value for the given key without updating its recency. See
+// [Map.Peek].
+func (c *Cache[K, V]) Peek(key K) (V, bool) { return c.m.Peek(key) }
+
+// Delete deletes the given key. See [Map.Delete].
+func (c *Cache[K, V]) Delete(key K) (V, bool) { return c.m.Delete(key) }
+
+// Take deletes the given key and returns its value. See [Map.Take].
+func (c *Cache[K, V]) Take(key K) (V, bool) { return c.m.Take(key) }
+
+// DeleteLRU evicts the least recently used entry. See [Map.DeleteLRU].
+func (c *Cache[K, V]) DeleteLRU() (key K, value V) { return c.m.DeleteLRU() }
+
+// Pin marks the entry for the given key as pinned, protecting it from
+// eviction. See [Map.Pin].
+func (c *Cache[K, V]) Pin(key K) bool { return c.m.Pin(key) }
+
+// Unpin unpins the entry for the given key. See [Map.Unpin].
+func (c *Cache[K, V]) Unpin(key K) bool { return c.m.Unpin(key) }
+
+// LRU returns the least recently used entry. See [Map.LRU].
+func (c *Cache[K, V]) LRU() (K, V) { return c.m.LRU() }
+
+// MRU returns the most recently used entry. See [Map.MRU].
+func (c *Cache[K, V]) MRU() (K, V) { return c.m.MRU() }
+
+// Keys returns an iterator for all keys in the Cache, lru first. See
+// [Map.Keys].
+func (c *Cache[K, V]) Keys() func(yield func(K) bool) { return c.m.Keys() }
+
+// Values returns an iterator for all values in the Cache, lru first. See
+// [Map.Values].
+func (c *Cache[K, V]) Values() func(yield func(V) bool) { return c.m.Values() }
+
+// All returns an iterator for all key value pairs in the Cache, lru first. See
+// [Map.All].
+func (c *Cache[K, V]) All() func(yield func(K, V) bool) { return c.m.All() }
+
+// AppendKeys appends all keys in the Cache to dst, mru first. See
+// [Map.AppendKeys].
+func (c *Cache[K, V]) AppendKeys(dst []K) []K { return c.m.AppendKeys(dst) }
+
+// AppendValues appends all values in the Cache to dst, mru first. See
+// [Map.AppendValues].
+func (c *Cache[K, V]) AppendValues(dst []V) []V { return c.m.AppendValues(dst) }
+
+// Len returns the number of entries in the Cache.
+func (c *Cache[K, V]) Len() int { return c.m.Len() }
+
+// MaxLen returns the maximum number of entries in the Cache, or 0 if the
+// number of entries is unbounded.
+func (c *Cache[K, V]) MaxLen() int { return c.maxLen }
+
+// Size returns the total size of all entries in the Cache. See [Map.Size].
+func (c *Cache[K, V]) Size() int64 { return c.m.Size() }
+
+// MaxSize returns the maximum total size of the entries in the Cache, or 0 if
+// the size is not bounded.
+func (c *Cache[K, V]) MaxSize() int64 { return c.maxSize }
+
+// Stats returns the usage statistics of the Cache. See [Map.Stats].
+func (c *Cache[K, V]) Stats() Stats { return c.m.Stats() }
+
+// ResetStats resets all usage counters to zero.
+func (c *Cache[K, V]) ResetStats() { c.m.ResetStats() }
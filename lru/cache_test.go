@@ -0,0 +1,342 @@
+package lru_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/db47h/cache/v2/hash"
+	"github.com/db47h/cache/v2/lru"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	const maxLen = 100
+	var evicted []int
+	c := lru.New[int, int](maxLen, lru.WithHasher(hash.Number[int]()),
+		lru.WithOnEvict(func(k, _ int) { evicted = append(evicted, k) }))
+	require.Equal(t, maxLen, c.MaxLen())
+	for i := range maxLen {
+		c.Set(i, i)
+	}
+	require.Empty(t, evicted)
+	// replacements do not evict
+	c.Set(0, 42)
+	require.Empty(t, evicted)
+	c.Set(maxLen, maxLen)
+	require.Equal(t, maxLen, c.Len())
+	require.Equal(t, []int{1}, evicted)
+	v, ok := c.Get(0)
+	require.True(t, ok)
+	require.Equal(t, 42, v)
+
+	k, _ := c.LRU()
+	require.Equal(t, 2, k)
+	k, _ = c.MRU()
+	require.Equal(t, 0, k)
+	require.Equal(t, maxLen, len(slices.Collect(c.Keys())))
+
+	_, ok = c.Delete(0)
+	require.True(t, ok)
+	c.Set(-1, -1)
+	require.Len(t, evicted, 1)
+	require.Equal(t, maxLen, c.Len())
+}
+
+func TestCache_noGrow(t *testing.T) {
+	for _, maxLen := range []int{1, 13, 14, 100, 1000, 1 << 12} {
+		c := lru.New[int, int](maxLen, lru.WithHasher(hash.Number[int]()))
+		for i := range maxLen {
+			c.Set(i, i)
+		}
+		n := testing.AllocsPerRun(10, func() {
+			for i := range 10 * maxLen {
+				c.Set(maxLen+i, i)
+			}
+		})
+		require.Zero(t, n, "maxLen %d", maxLen)
+		require.Equal(t, maxLen, c.Len())
+	}
+}
+
+func TestCache_maxSize(t *testing.T) {
+	var evicted []string
+	c := lru.New[string, int](100,
+		lru.WithSizer(func(v int) int64 { return int64(v) }),
+		lru.WithMaxSize(40),
+		lru.WithOnEvict(func(k string, _ int) { evicted = append(evicted, k) }))
+	require.Equal(t, int64(40), c.MaxSize())
+	for _, d := range td {
+		c.Set(d.key, d.value)
+	}
+	require.Equal(t, int64(36), c.Size())
+	require.Empty(t, evicted)
+
+	// several small entries make room for a large one
+	c.Set("sun", 20)
+	require.Equal(t, []string{"mercury", "venus", "earth", "mars", "jupiter", "saturn"}, evicted)
+	require.Equal(t, int64(7+8+20), c.Size())
+
+	// growing entries
+	evicted = nil
+	c.Set("uranus", 12)
+	require.Empty(t, evicted)
+	c.Set("neptune", 10)
+	require.Equal(t, []string{"sun"}, evicted)
+	require.Equal(t, int64(12+10), c.Size())
+
+	require.Equal(t, 1, c.EvictToSize(10))
+	require.Equal(t, int64(10), c.Size())
+	k, _ := c.LRU()
+	require.Equal(t, "neptune", k)
+}
+
+func TestCache_replaceTooLarge(t *testing.T) {
+	var evicted []string
+	c := lru.New[string, int](100,
+		lru.WithSizer(func(v int) int64 { return int64(v) }),
+		lru.WithMaxSize(40),
+		lru.WithOnEvict(func(k string, _ int) { evicted = append(evicted, k) }))
+	for _, d := range td {
+		c.Set(d.key, d.value)
+	}
+	prev, replaced := c.Set("earth", 41)
+	require.False(t, replaced)
+	require.Zero(t, prev)
+	require.Empty(t, evicted)
+	require.Equal(t, len(td), c.Len())
+	require.Equal(t, int64(36), c.Size())
+	v, ok := c.Peek("earth")
+	require.True(t, ok)
+	require.Equal(t, 3, v)
+	// the largest possible value is accepted
+	prev, replaced = c.Set("earth", 40)
+	require.True(t, replaced)
+	require.Equal(t, 3, prev)
+	require.Equal(t, len(td)-1, len(evicted))
+	require.Equal(t, []string{"earth"}, slices.Collect(c.Keys()))
+}
+
+func TestCache_SetMaxLen(t *testing.T) {
+	var evicted int
+	c := lru.New[int, int](1000, lru.WithHasher(hash.Number[int]()),
+		lru.WithOnEvict(func(int, int) { evicted++ }))
+	for i := range 1000 {
+		c.Set(i, i)
+	}
+	require.Zero(t, c.SetMaxLen(2000))
+	require.Equal(t, 900, c.SetMaxLen(100))
+	require.Equal(t, 900, evicted)
+	require.Equal(t, 100, c.Len())
+	require.Equal(t, 100, c.MaxLen())
+	k, _ := c.LRU()
+	require.Equal(t, 900, k)
+	c.Set(-1, -1)
+	require.Equal(t, 100, c.Len())
+}
+
+func TestCache_SetMaxSize(t *testing.T) {
+	c := lru.New[string, int](100, lru.WithSizer(func(v int) int64 { return int64(v) }))
+	for _, d := range td {
+		c.Set(d.key, d.value)
+	}
+	require.Zero(t, c.SetMaxSize(36))
+	for _, max := range []int64{35, 20, 8, 1} {
+		c.SetMaxSize(max)
+		require.LessOrEqual(t, c.Size(), max)
+		require.Equal(t, max, c.MaxSize())
+	}
+	require.Zero(t, c.Len())
+	c.SetMaxSize(-1)
+	c.Set("sun", 1000)
+	require.Equal(t, 1, c.Len())
+}
+
+func TestCache_insertTooLarge(t *testing.T) {
+	var evicted int
+	c := lru.New[string, int](100,
+		lru.WithSizer(func(v int) int64 { return int64(v) }),
+		lru.WithMaxSize(36),
+		lru.WithOnEvict(func(string, int) { evicted++ }))
+	for _, d := range td {
+		c.Set(d.key, d.value)
+	}
+	_, _, ok := c.TrySet("sun", 1000)
+	require.False(t, ok)
+	c.SetWithTTL("sun", 37, time.Hour)
+	require.Zero(t, evicted)
+	_, ok = c.Peek("sun")
+	require.False(t, ok)
+	require.Equal(t, len(td), c.Len())
+	require.Equal(t, int64(36), c.Size())
+	for _, d := range td {
+		v, ok := c.Peek(d.key)
+		require.True(t, ok)
+		require.Equal(t, d.value, v)
+	}
+}
+
+func TestCache_UpdateSize(t *testing.T) {
+	type buf struct{ b []byte }
+	var evicted []string
+	c := lru.New[string, *buf](0,
+		lru.WithSizer(func(v *buf) int64 { return int64(len(v.b)) }),
+		lru.WithMaxSize(30),
+		lru.WithOnEvict(func(k string, _ *buf) { evicted = append(evicted, k) }))
+	a, b, d := &buf{make([]byte, 10)}, &buf{make([]byte, 10)}, &buf{make([]byte, 10)}
+	c.Set("a", a)
+	c.Set("b", b)
+	c.Set("d", d)
+
+	// growing d evicts the LRU entry
+	d.b = append(d.b, 5)
+	require.True(t, c.UpdateSize("d", 10))
+	require.Equal(t, []string{"a"}, evicted)
+	require.Equal(t, int64(21), c.Size())
+
+	// shrinking does not evict
+	d.b = d.b[:1]
+	require.True(t, c.UpdateSize("d", 11))
+	require.Equal(t, int64(11), c.Size())
+
+	// too large: only d is evicted
+	d.b = make([]byte, 31)
+	require.False(t, c.UpdateSize("d", 1))
+	require.Equal(t, []string{"a", "d"}, evicted)
+	require.Equal(t, int64(10), c.Size())
+	require.Equal(t, 1, c.Len())
+
+	require.False(t, c.UpdateSize("a", 10))
+}
+
+func TestCache_pinnedSize(t *testing.T) {
+	var evicted []int
+	c := lru.New[int, int](0, lru.WithHasher(hash.Number[int]()),
+		lru.WithSizer(func(v int) int64 { return int64(v) }),
+		lru.WithMaxSize(10),
+		lru.WithOnEvict(func(k, _ int) { evicted = append(evicted, k) }))
+	c.Set(1, 8)
+	require.True(t, c.Pin(1))
+	_, _, ok := c.TrySet(2, 5)
+	require.False(t, ok)
+	_, _, _, evicted2, ok := c.SetReport(2, 5)
+	require.False(t, ok)
+	require.False(t, evicted2)
+	_, ok = c.Peek(2)
+	require.False(t, ok)
+	require.Empty(t, evicted)
+
+	// fits along with the pinned entry
+	_, _, ok = c.TrySet(2, 2)
+	require.True(t, ok)
+	// replacing a pinned entry
+	require.True(t, c.Pin(2))
+	_, replaced, ok := c.TrySet(1, 9)
+	require.False(t, ok)
+	require.False(t, replaced)
+	_, replaced, ok = c.TrySet(1, 7)
+	require.True(t, ok)
+	require.True(t, replaced)
+	require.Equal(t, int64(9), c.Size())
+	require.Empty(t, evicted)
+}
+
+func TestCache_SetReport(t *testing.T) {
+	c := lru.New[int, int](3, lru.WithHasher(hash.Number[int]()))
+	for i := range 3 {
+		_, _, _, evicted, ok := c.SetReport(i, i)
+		require.False(t, evicted)
+		require.True(t, ok)
+	}
+	c.Get(0)
+	// replacing does not evict
+	prev, replaced, _, evicted, _ := c.SetReport(2, 20)
+	require.True(t, replaced)
+	require.Equal(t, 2, prev)
+	require.False(t, evicted)
+
+	_, replaced, k, evicted, ok := c.SetReport(3, 3)
+	require.False(t, replaced)
+	require.True(t, evicted)
+	require.True(t, ok)
+	require.Equal(t, 1, k)
+	_, ok = c.Peek(1)
+	require.False(t, ok)
+	require.Equal(t, 3, c.Len())
+}
+
+func TestCache_Pin(t *testing.T) {
+	var evicted []int
+	c := lru.New[int, int](3, lru.WithHasher(hash.Number[int]()),
+		lru.WithOnEvict(func(k, _ int) { evicted = append(evicted, k) }))
+	for i := range 3 {
+		c.Set(i, i)
+	}
+	require.True(t, c.Pin(0))
+	require.False(t, c.Pin(42))
+
+	// the pinned LRU entry is skipped
+	c.Set(3, 3)
+	require.Equal(t, []int{1}, evicted)
+	k, _ := c.LRU()
+	require.Equal(t, 0, k)
+
+	// all entries pinned: new keys are rejected, existing ones updated
+	require.True(t, c.Pin(2))
+	require.True(t, c.Pin(3))
+	_, replaced, ok := c.TrySet(4, 4)
+	require.False(t, replaced)
+	require.False(t, ok)
+	_, _, _, _, ok = c.SetReport(4, 4)
+	require.False(t, ok)
+	_, ok = c.Peek(4)
+	require.False(t, ok)
+	prev, replaced, ok := c.TrySet(3, 30)
+	require.True(t, replaced)
+	require.True(t, ok)
+	require.Equal(t, 3, prev)
+	require.Equal(t, 3, c.Len())
+	k, _ = c.DeleteLRU()
+	require.Zero(t, k)
+	require.Equal(t, 3, c.Len())
+	require.Equal(t, []int{1}, evicted)
+
+	// shrinking does not evict pinned entries either
+	require.Zero(t, c.SetMaxLen(1))
+	require.Equal(t, 3, c.Len())
+
+	require.True(t, c.Unpin(2))
+	require.Zero(t, c.SetMaxLen(3))
+	c.Set(4, 4)
+	require.Equal(t, []int{1, 2}, evicted)
+	require.Equal(t, 3, c.Len())
+}
+
+func TestCache_unbounded(t *testing.T) {
+	const n = 100000
+	c := lru.New[int, int](0, lru.WithHasher(hash.Number[int]()),
+		lru.WithOnEvict(func(int, int) { t.Fatal("unexpected eviction") }))
+	require.Zero(t, c.MaxLen())
+	for i := range n {
+		c.Set(i, i)
+	}
+	require.Equal(t, n, c.Len())
+	for i := range n {
+		v, ok := c.Peek(i)
+		require.True(t, ok)
+		require.Equal(t, i, v)
+	}
+
+	c = lru.New[int, int](-1, lru.WithHasher(hash.Number[int]()))
+	for i := range 100 {
+		c.Set(i, i)
+	}
+	// manual eviction still works
+	k, _ := c.DeleteLRU()
+	require.Equal(t, 0, k)
+	require.Equal(t, 98, c.SetMaxLen(1))
+	require.Equal(t, 1, c.Len())
+	require.Zero(t, c.SetMaxLen(0))
+	c.Set(1000, 1000)
+	require.Equal(t, 2, c.Len())
+}
@@ -18,20 +18,24 @@ type CachedFile struct {
 	Size    int64
 }
 
+// HttpCache caches files fetched over HTTP on disk, up to a maximum total
+// size.
 type HttpCache struct {
-	lru  lru.Map[string, CachedFile]
-	size int64
-	cap  int64
+	lru *lru.Cache[string, CachedFile]
 }
 
 func NewHttpCache(capacity int) *HttpCache {
-	c := new(HttpCache)
-	c.cap = int64(capacity)
-	return c
+	return &HttpCache{
+		lru: lru.New[string, CachedFile](0,
+			lru.WithSizer(func(cf CachedFile) int64 { return cf.Size }),
+			lru.WithMaxSize(int64(capacity)),
+			lru.WithOnEvict(onEvict)),
+	}
 }
 
-func (c *HttpCache) onEvict(url string, cf CachedFile) bool {
-	return false
+// onEvict removes evicted files from disk.
+func onEvict(url string, cf CachedFile) {
+	os.Remove(cf.Path)
 }
 
 func (c *HttpCache) Get(url string) ([]byte, error) {
@@ -75,7 +79,12 @@ func (c *HttpCache) Get(url string) ([]byte, error) {
 		return nil, err
 	}
 
-	c.lru.Set(url, cf)
+	// least recently used files are evicted as needed to stay within
+	// capacity.
+	if _, _, ok := c.lru.TrySet(url, cf); !ok {
+		// too large to be cached
+		os.Remove(cf.Path)
+	}
 	return data, nil
 }
 
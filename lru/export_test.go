@@ -0,0 +1,9 @@
+package lru
+
+// SetNanotime replaces the clock used for TTL computations and returns a
+// function that restores the original one.
+func SetNanotime(f func() int64) (restore func()) {
+	old := nanotime
+	nanotime = f
+	return func() { nanotime = old }
+}
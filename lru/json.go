@@ -0,0 +1,71 @@
+package lru
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalJSON implements [json.Marshaler]. Live entries are encoded as a JSON
+// array of [key, value] pairs, mru first. Both K and V must be encodable by
+// [encoding/json].
+func (m *Map[K, V]) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	now := nanotime()
+	first := true
+	for i := m.mru(); i != 0; i = m.elms[i].next {
+		it := &m.elms[i]
+		if it.expires != 0 && it.expires <= now {
+			continue
+		}
+		k, err := json.Marshal(it.key)
+		if err != nil {
+			return nil, fmt.Errorf("lru: cannot marshal key: %w", err)
+		}
+		v, err := json.Marshal(it.value)
+		if err != nil {
+			return nil, fmt.Errorf("lru: cannot marshal value: %w", err)
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		buf.WriteByte('[')
+		buf.Write(k)
+		buf.WriteByte(',')
+		buf.Write(v)
+		buf.WriteByte(']')
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON implements [json.Unmarshaler]. It replaces the contents of the
+// Map with entries encoded by [Map.MarshalJSON], restoring their LRU order.
+// Expiration times are not preserved.
+func (m *Map[K, V]) UnmarshalJSON(data []byte) error {
+	var pairs [][2]json.RawMessage
+	if err := json.Unmarshal(data, &pairs); err != nil {
+		return fmt.Errorf("lru: %w", err)
+	}
+	type entry struct {
+		key   K
+		value V
+	}
+	es := make([]entry, len(pairs))
+	for i, p := range pairs {
+		if err := json.Unmarshal(p[0], &es[i].key); err != nil {
+			return fmt.Errorf("lru: cannot unmarshal key: %w", err)
+		}
+		if err := json.Unmarshal(p[1], &es[i].value); err != nil {
+			return fmt.Errorf("lru: cannot unmarshal value: %w", err)
+		}
+	}
+	m.Clear()
+	m.Grow(len(es))
+	for i := len(es) - 1; i >= 0; i-- {
+		m.Set(es[i].key, es[i].value)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package lru_test
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/db47h/cache/v2/lru"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMap_MarshalJSON(t *testing.T) {
+	m := populate()
+	m.Get("earth")
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	require.JSONEq(t, `[["earth",3],["neptune",8],["uranus",7],["saturn",6],["jupiter",5],["mars",4],["venus",2],["mercury",1]]`, string(data))
+
+	var r lru.Map[string, int]
+	r.Set("pluto", 9)
+	require.NoError(t, json.Unmarshal(data, &r))
+	require.Equal(t, m.Len(), r.Len())
+	require.Equal(t, slices.Collect(m.Keys()), slices.Collect(r.Keys()))
+	require.Equal(t, slices.Collect(m.Values()), slices.Collect(r.Values()))
+
+	var e lru.Map[string, int]
+	data, err = json.Marshal(&e)
+	require.NoError(t, err)
+	require.Equal(t, "[]", string(data))
+
+	// unsupported types
+	f := lru.NewMap[int, func()]()
+	f.Set(1, func() {})
+	_, err = json.Marshal(f)
+	require.Error(t, err)
+	require.Error(t, json.Unmarshal([]byte(`[[1,"x"]]`), &r))
+}
@@ -1,9 +1,12 @@
 package lru_test
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"slices"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -158,6 +161,276 @@ func TestMap_Get(t *testing.T) {
 	}
 }
 
+func TestMap_GetRef(t *testing.T) {
+	m := populate()
+	p, ok := m.GetRef("mercury")
+	require.True(t, ok)
+	*p = 42
+	k, _ := m.MRU()
+	require.Equal(t, "mercury", k)
+	v, ok := m.Get("mercury")
+	require.True(t, ok)
+	require.Equal(t, 42, v)
+
+	p, ok = m.GetRef("pluto")
+	require.False(t, ok)
+	require.Nil(t, p)
+}
+
+func TestMap_UpdateSize(t *testing.T) {
+	m := lru.NewMap[string, []byte](lru.WithSizer(func(v []byte) int64 { return int64(len(v)) }))
+	m.Set("a", make([]byte, 10))
+	m.Set("b", make([]byte, 5))
+	p, _ := m.GetRef("a")
+	old := int64(len(*p))
+	*p = append(*p, make([]byte, 20)...)
+	require.True(t, m.UpdateSize("a", old))
+	require.Equal(t, int64(35), m.Size())
+	k, _ := m.LRU()
+	require.Equal(t, "b", k)
+	require.False(t, m.UpdateSize("c", 0))
+	require.Equal(t, int64(35), m.Size())
+}
+
+func TestMap_Take(t *testing.T) {
+	m := lru.NewMap[string, int](lru.WithStats())
+	for _, d := range td {
+		m.Set(d.key, d.value)
+	}
+	v, ok := m.Take("earth")
+	require.True(t, ok)
+	require.Equal(t, 3, v)
+	require.Equal(t, len(td)-1, m.Len())
+	_, ok = m.Peek("earth")
+	require.False(t, ok)
+	v, ok = m.Take("earth")
+	require.False(t, ok)
+	require.Zero(t, v)
+	require.Equal(t, len(td)-1, m.Len())
+	st := m.Stats()
+	require.Equal(t, uint64(1), st.Hits)
+	require.Equal(t, uint64(1), st.Misses)
+}
+
+func TestMap_Touch(t *testing.T) {
+	m := populate()
+	require.True(t, m.Touch("mercury"))
+	k, _ := m.MRU()
+	require.Equal(t, "mercury", k)
+	k, _ = m.LRU()
+	require.Equal(t, "venus", k)
+
+	keys := slices.Collect(m.Keys())
+	require.False(t, m.Touch("pluto"))
+	require.Equal(t, keys, slices.Collect(m.Keys()))
+}
+
+func TestMap_Reset(t *testing.T) {
+	opts := []lru.Option{lru.WithHasher(hash.Number[int]()), lru.WithCapacity(64),
+		lru.WithPolicy(lru.PolicyLFU), lru.WithStats()}
+	m := lru.NewMap[int, int](opts...)
+	fill := func() {
+		for i := range 40 {
+			m.Set(i, i)
+			m.Get(i)
+		}
+	}
+	fill()
+	m.Reset(opts...)
+	require.Zero(t, m.Len())
+	require.Zero(t, m.Stats())
+	require.Equal(t, 64, m.Capacity())
+
+	// different capacity
+	fill()
+	m.Reset(lru.WithHasher(hash.Number[int]()), lru.WithCapacity(256))
+	require.Zero(t, m.Len())
+	require.Equal(t, 256, m.Capacity())
+	fill()
+	require.Equal(t, 40, m.Len())
+	for i := range 40 {
+		v, ok := m.Peek(i)
+		require.True(t, ok)
+		require.Equal(t, i, v)
+	}
+
+	// options not given again are reset
+	evicted := 0
+	m.Reset(lru.WithSizer(func(int) int64 { return 10 }), lru.WithOnEvict(func(int, int) { evicted++ }),
+		lru.WithOnEvictReason(func(int, int, lru.EvictReason) { evicted++ }))
+	fill()
+	require.Equal(t, int64(400), m.Size())
+	m.Reset()
+	fill()
+	m.DeleteLRU()
+	require.Zero(t, evicted)
+	require.Zero(t, m.Size())
+
+	// same capacity, different load factor
+	m.Reset(opts[:2]...)
+	m.Reset(append(opts[:2:2], lru.WithMaxLoadFactor(0.5))...)
+	require.Equal(t, 64, m.Capacity())
+	fill()
+	require.Equal(t, 128, m.Capacity())
+}
+
+func TestMap_SortedKeys(t *testing.T) {
+	less := func(a, b string) bool { return a < b }
+	var e lru.Map[string, int]
+	keys := e.SortedKeys(less)
+	require.NotNil(t, keys)
+	require.Empty(t, keys)
+
+	m := populate()
+	order := slices.Collect(m.Keys())
+	keys = m.SortedKeys(less)
+	require.True(t, slices.IsSorted(keys))
+	require.ElementsMatch(t, order, keys)
+	require.Equal(t, order, slices.Collect(m.Keys()))
+	keys[0] = "pluto"
+	require.Equal(t, order, slices.Collect(m.Keys()))
+}
+
+func TestMap_AppendKeys(t *testing.T) {
+	m := populate()
+	keys := m.AppendKeys([]string{"sun"})
+	require.Equal(t, append([]string{"sun"}, slices.Collect(m.KeysMRU())...), keys)
+	values := m.AppendValues(nil)
+	require.Equal(t, slices.Collect(m.ValuesMRU()), values)
+
+	kbuf := make([]string, 0, m.Len())
+	vbuf := make([]int, 0, m.Len())
+	allocs := testing.AllocsPerRun(10, func() {
+		kbuf = m.AppendKeys(kbuf[:0])
+		vbuf = m.AppendValues(vbuf[:0])
+	})
+	require.Zero(t, allocs)
+	require.Equal(t, keys[1:], kbuf)
+	require.Equal(t, values, vbuf)
+}
+
+func TestMap_SetOrdered(t *testing.T) {
+	type kv struct{ k, v int }
+	collect := func(m *lru.Map[int, int]) (r []kv) {
+		for k, v := range m.All() {
+			r = append(r, kv{k, v})
+		}
+		return r
+	}
+	for _, p := range []lru.Policy{lru.PolicyLRU, lru.PolicyFIFO, lru.PolicyClock} {
+		old := lru.NewMap[int, int](lru.WithPolicy(p), lru.WithHasher(hash.Number[int]()))
+		xo := New64S()
+		for range 2000 {
+			k := xo.IntN(500)
+			if _, ok := old.Get(k); !ok {
+				old.Set(k, -k)
+			}
+		}
+		m := lru.NewMap[int, int](lru.WithPolicy(p), lru.WithHasher(hash.Number[int]()))
+		m.SetOrdered(old.All())
+		require.Equal(t, collect(old), collect(m), p)
+
+		// replaced entries are re-promoted
+		m = lru.NewMap[int, int](lru.WithPolicy(p), lru.WithHasher(hash.Number[int]()))
+		for k := range old.KeysMRU() {
+			m.Set(k, k)
+		}
+		m.Set(-1, 1)
+		m.SetOrdered(old.All())
+		require.Equal(t, append([]kv{{-1, 1}}, collect(old)...), collect(m), p)
+	}
+}
+
+func TestMap_SetMulti(t *testing.T) {
+	m := lru.NewMap[int, int](lru.WithHasher(hash.Number[int]()))
+	m.Set(5, 0)
+	keys := make([]int, 1000)
+	values := make([]int, 1000)
+	for i := range keys {
+		keys[i] = i
+		values[i] = -i
+	}
+	require.Equal(t, 999, m.SetMulti(keys, values))
+	require.Equal(t, 1000, m.Len())
+	require.Equal(t, 2048, m.Capacity())
+	for i := range 1000 {
+		v, ok := m.Peek(i)
+		require.True(t, ok)
+		require.Equal(t, -i, v)
+	}
+	k, _ := m.MRU()
+	require.Equal(t, 999, k)
+
+	require.Panics(t, func() { m.SetMulti(keys, values[1:]) })
+}
+
+func TestMap_GetWithDefault(t *testing.T) {
+	m := populate()
+	calls := 0
+	fn := func(k string) (int, error) {
+		calls++
+		if k == "vulcan" {
+			return 0, errors.New("no such planet")
+		}
+		return len(k), nil
+	}
+	v, err := m.GetWithDefault("mercury", fn)
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+	require.Zero(t, calls)
+	k, _ := m.MRU()
+	require.Equal(t, "mercury", k)
+
+	v, err = m.GetWithDefault("vulcan", fn)
+	require.Error(t, err)
+	require.Equal(t, 1, calls)
+	require.Equal(t, len(td), m.Len())
+	k, _ = m.MRU()
+	require.Equal(t, "mercury", k)
+
+	v, err = m.GetWithDefault("pluto", fn)
+	require.NoError(t, err)
+	require.Equal(t, 5, v)
+	require.Equal(t, 2, calls)
+	v, err = m.GetWithDefault("pluto", fn)
+	require.NoError(t, err)
+	require.Equal(t, 5, v)
+	require.Equal(t, 2, calls)
+	require.Equal(t, len(td)+1, m.Len())
+}
+
+func TestMap_GetWithDefault_doNotCache(t *testing.T) {
+	m := populate()
+	evicted := 0
+	m.SetOnEvict(func(string, int) { evicted++ })
+	calls := 0
+	fn := func(k string) (int, error) {
+		calls++
+		return -1, fmt.Errorf("%s: %w", k, lru.ErrDoNotCache)
+	}
+	for i := range 2 {
+		v, err := m.GetWithDefault("vulcan", fn)
+		require.NoError(t, err)
+		require.Equal(t, -1, v)
+		require.Equal(t, i+1, calls)
+		require.Equal(t, len(td), m.Len())
+	}
+	require.Zero(t, evicted)
+}
+
+func TestMap_GetMulti(t *testing.T) {
+	m := populate()
+	values, found := m.GetMulti([]string{"venus", "pluto", "mercury", "vulcan", "mars"})
+	require.Equal(t, []int{2, 0, 1, 0, 4}, values)
+	require.Equal(t, []bool{true, false, true, false, true}, found)
+	require.Equal(t, []string{"earth", "jupiter", "saturn", "uranus", "neptune", "venus", "mercury", "mars"},
+		slices.Collect(m.Keys()))
+
+	values, found = m.GetMulti(nil)
+	require.Empty(t, values)
+	require.Empty(t, found)
+}
+
 func TestMap_All(t *testing.T) {
 	m := populate()
 	i := 0
@@ -203,6 +476,239 @@ func TestMap_Values(t *testing.T) {
 	}
 }
 
+func TestMap_Ordered(t *testing.T) {
+	m := populate()
+	// leave some tombstones
+	m.Delete(td[2].key)
+	m.Delete(td[5].key)
+	m.Get(td[0].key)
+	var keys []string
+	m.Ordered()(func(pos int, k string, v int) bool {
+		require.Equal(t, len(keys), pos)
+		if pos == 0 {
+			mk, mv := m.MRU()
+			require.Equal(t, mk, k)
+			require.Equal(t, mv, v)
+		}
+		keys = append(keys, k)
+		return true
+	})
+	require.Len(t, keys, m.Len())
+	lk, _ := m.LRU()
+	require.Equal(t, lk, keys[len(keys)-1])
+	require.Equal(t, slices.Collect(m.KeysMRU()), keys)
+
+	n := 0
+	m.Ordered()(func(pos int, _ string, _ int) bool {
+		n++
+		return pos < 2
+	})
+	require.Equal(t, 3, n)
+}
+
+func TestMap_Entries(t *testing.T) {
+	m := populate()
+	i := 0
+	for k, v := range m.Entries() {
+		require.Equal(t, td[i].key, k)
+		*v *= 10
+		i++
+	}
+	require.Equal(t, len(td), i)
+	for _, d := range td {
+		v, ok := m.Get(d.key)
+		require.True(t, ok)
+		require.Equal(t, d.value*10, v)
+	}
+}
+
+func TestMap_AllMRU(t *testing.T) {
+	m := populate()
+	i := len(td)
+	for k, v := range m.AllMRU() {
+		i--
+		it := &td[i]
+		if k != it.key || v != it.value {
+			t.Fatalf("AllMRU(): expected %s, %d; got %s, %d", it.key, it.value, k, v)
+		}
+	}
+	require.Zero(t, i)
+
+	i = len(td)
+	for k := range m.KeysMRU() {
+		i--
+		require.Equal(t, td[i].key, k)
+		if i == 4 {
+			break
+		}
+	}
+	require.Equal(t, 4, i)
+
+	i = len(td)
+	for v := range m.ValuesMRU() {
+		i--
+		require.Equal(t, td[i].value, v)
+	}
+	require.Zero(t, i)
+
+	var e lru.Map[string, int]
+	for range e.AllMRU() {
+		t.Fatal("AllMRU on empty map")
+	}
+	e.Set("sun", 0)
+	n := 0
+	for k := range e.KeysMRU() {
+		require.Equal(t, "sun", k)
+		n++
+	}
+	for k := range e.Keys() {
+		require.Equal(t, "sun", k)
+		n++
+	}
+	require.Equal(t, 2, n)
+}
+
+func TestMap_deleteWhileIterating(t *testing.T) {
+	check := func(t *testing.T, m *lru.Map[string, int]) {
+		t.Helper()
+		require.Equal(t, len(td)/2, m.Len())
+		i := 1
+		for k, v := range m.All() {
+			require.Equal(t, td[i].key, k)
+			require.Equal(t, td[i].value, v)
+			i += 2
+		}
+	}
+	t.Run("All", func(t *testing.T) {
+		m := populate()
+		for k, v := range m.All() {
+			if v%2 != 0 {
+				m.Delete(k)
+			}
+		}
+		check(t, m)
+	})
+	t.Run("Keys", func(t *testing.T) {
+		m := populate()
+		i := 0
+		for k := range m.Keys() {
+			if i%2 == 0 {
+				m.Delete(k)
+			}
+			i++
+		}
+		check(t, m)
+	})
+	t.Run("Values", func(t *testing.T) {
+		m := populate()
+		for v := range m.Values() {
+			if v%2 != 0 {
+				m.Delete(td[v-1].key)
+			}
+		}
+		check(t, m)
+	})
+	t.Run("AllMRU", func(t *testing.T) {
+		m := populate()
+		for k, v := range m.AllMRU() {
+			if v%2 != 0 {
+				m.Delete(k)
+			}
+		}
+		check(t, m)
+	})
+}
+
+func TestMap_DeleteFunc(t *testing.T) {
+	m := lru.NewMap[int, int](lru.WithSizer(func(v int) int64 { return int64(v) }))
+	for i := range 1000 {
+		m.Set(i, i)
+	}
+	n := m.DeleteFunc(func(k, v int) bool { return k%3 == 0 })
+	require.Equal(t, 334, n)
+	require.Equal(t, 1000-334, m.Len())
+	var sz int64
+	for k, v := range m.All() {
+		require.NotZero(t, k%3)
+		sz += int64(v)
+	}
+	require.Equal(t, sz, m.Size())
+	for i := range 1000 {
+		_, ok := m.Get(i)
+		require.Equal(t, i%3 != 0, ok)
+	}
+
+	// deleting the current entry from del
+	n = m.DeleteFunc(func(k, v int) bool {
+		if k%3 == 1 {
+			m.Delete(k)
+			return true
+		}
+		return false
+	})
+	require.Equal(t, 333, n)
+	require.Equal(t, 333, m.Len())
+	for k := range m.Keys() {
+		require.Equal(t, 2, k%3)
+	}
+}
+
+func TestMap_Clone(t *testing.T) {
+	m := populate()
+	c := m.Clone()
+	require.Equal(t, slices.Collect(m.Keys()), slices.Collect(c.Keys()))
+
+	// grow the clone
+	for i := range 100 {
+		c.Set(strconv.Itoa(i), i)
+	}
+	c.Delete("mercury")
+	c.Set("venus", 42)
+	require.Equal(t, len(td), m.Len())
+	i := 0
+	for k, v := range m.All() {
+		require.Equal(t, td[i].key, k)
+		require.Equal(t, td[i].value, v)
+		i++
+	}
+	require.Equal(t, len(td)+99, c.Len())
+
+	var e lru.Map[string, int]
+	c = e.Clone()
+	require.Equal(t, 0, c.Len())
+	c.Set("sun", 0)
+	_, ok := c.Get("sun")
+	require.True(t, ok)
+	require.Equal(t, 0, e.Len())
+}
+
+func TestMap_Merge(t *testing.T) {
+	m := lru.NewMap[int, int](lru.WithCapacity(16))
+	o := lru.NewMap[int, int]()
+	for i := range 10 {
+		m.Set(i, i)
+	}
+	for i := 50; i > 5; i-- {
+		o.Set(i, -i)
+	}
+	m.Merge(o)
+	require.Equal(t, 51, m.Len())
+	var keys []int
+	for k, v := range m.All() {
+		keys = append(keys, k)
+		if k > 5 {
+			require.Equal(t, -k, v)
+		} else {
+			require.Equal(t, k, v)
+		}
+	}
+	require.Equal(t, []int{0, 1, 2, 3, 4, 5}, keys[:6])
+	require.Equal(t, slices.Collect(o.Keys()), keys[6:])
+
+	m.Merge(m)
+	require.Equal(t, 51, m.Len())
+}
+
 func TestMap_Delete(t *testing.T) {
 	xo := New64S()
 	m := populate()
@@ -218,6 +724,643 @@ func TestMap_Delete(t *testing.T) {
 	}
 }
 
+func TestMap_Size(t *testing.T) {
+	m := lru.NewMap[string, string](lru.WithSizer(func(v string) int64 { return int64(len(v)) }))
+	m.Set("a", "foo")
+	m.Set("b", "quux")
+	require.Equal(t, int64(7), m.Size())
+	// replace
+	m.Set("a", "foobar")
+	require.Equal(t, int64(10), m.Size())
+	m.Delete("b")
+	require.Equal(t, int64(6), m.Size())
+	m.DeleteLRU()
+	require.Equal(t, int64(0), m.Size())
+}
+
+func TestMap_EvictToSize(t *testing.T) {
+	var evicted int
+	m := lru.NewMap[string, int](lru.WithSizer(func(v int) int64 { return int64(v) }),
+		lru.WithOnEvict(func(string, int) { evicted++ }))
+	for _, d := range td {
+		m.Set(d.key, d.value)
+	}
+	// 1+2+...+8 = 36
+	require.Equal(t, int64(36), m.Size())
+	require.Zero(t, m.EvictToSize(36))
+	require.Equal(t, 3, m.EvictToSize(30))
+	require.Equal(t, 3, evicted)
+	// mercury, venus and earth must be gone
+	require.Equal(t, len(td)-3, m.Len())
+	require.Equal(t, int64(30), m.Size())
+	k, _ := m.LRU()
+	require.Equal(t, "mars", k)
+
+	// entry larger than max
+	m.Set("sun", 1000)
+	require.Equal(t, 6, m.EvictToSize(100))
+	require.Equal(t, 9, evicted)
+	require.Equal(t, 0, m.Len())
+	require.Equal(t, int64(0), m.Size())
+}
+
+func TestMap_EvictToLimits(t *testing.T) {
+	m := lru.NewMap[string, int](lru.WithSizer(func(v int) int64 { return int64(v) }))
+	for _, d := range td {
+		m.Set(d.key, d.value)
+	}
+	require.Zero(t, m.EvictToLimits(len(td), 36))
+	// count binds first: 6 entries left, size 3+...+8 = 33
+	require.Equal(t, 2, m.EvictToLimits(6, 35))
+	require.Equal(t, 6, m.Len())
+	require.Equal(t, int64(33), m.Size())
+	// bytes bind first: 8+7 = 15
+	require.Equal(t, 4, m.EvictToLimits(5, 15))
+	require.Equal(t, 2, m.Len())
+	require.Equal(t, int64(15), m.Size())
+	k, _ := m.LRU()
+	require.Equal(t, "uranus", k)
+
+	require.Equal(t, 2, m.EvictToLimits(0, 100))
+	require.Zero(t, m.Len())
+	require.Zero(t, m.EvictToLimits(-1, -1))
+}
+
+func TestMap_PopMRU(t *testing.T) {
+	clk := newFakeClock(t)
+	var evicted []string
+	m := lru.NewMap[string, int](lru.WithOnEvict(func(k string, _ int) { evicted = append(evicted, k) }))
+	_, _, ok := m.PopMRU()
+	require.False(t, ok)
+
+	m.Set("mercury", 1)
+	m.Set("venus", 2)
+	m.Set("earth", 3)
+	k, v, ok := m.PopMRU()
+	require.True(t, ok)
+	require.Equal(t, "earth", k)
+	require.Equal(t, 3, v)
+	k, _ = m.MRU()
+	require.Equal(t, "venus", k)
+
+	m.Set("mars", 4)
+	k, _ = m.DeleteLRU()
+	require.Equal(t, "mercury", k)
+	k, _, _ = m.PopMRU()
+	require.Equal(t, "mars", k)
+	require.Equal(t, []string{"mercury"}, evicted)
+
+	m.SetWithTTL("jupiter", 5, time.Second)
+	clk.advance(time.Minute)
+	k, v, ok = m.PopMRU()
+	require.True(t, ok)
+	require.Equal(t, "venus", k)
+	require.Equal(t, 2, v)
+	require.Equal(t, []string{"mercury", "jupiter"}, evicted)
+	require.Zero(t, m.Len())
+	_, _, ok = m.PopMRU()
+	require.False(t, ok)
+}
+
+func TestMap_EvictN(t *testing.T) {
+	var evicted []string
+	m := lru.NewMap[string, int](lru.WithOnEvict(func(k string, _ int) { evicted = append(evicted, k) }))
+	for _, d := range td {
+		m.Set(d.key, d.value)
+	}
+	require.Zero(t, m.EvictN(0))
+	require.Zero(t, m.EvictN(-1))
+	require.Equal(t, len(td), m.Len())
+	require.Equal(t, 3, m.EvictN(3))
+	require.Equal(t, []string{"mercury", "venus", "earth"}, evicted)
+	require.Equal(t, len(td)-3, m.EvictN(100))
+	require.Len(t, evicted, len(td))
+	require.Zero(t, m.Len())
+	require.Zero(t, m.EvictN(1))
+}
+
+func TestMap_Drain(t *testing.T) {
+	var evicted []string
+	m := lru.NewMap[string, int](lru.WithSizer(func(v int) int64 { return int64(v) }),
+		lru.WithOnEvict(func(k string, _ int) { evicted = append(evicted, k) }))
+	for _, d := range td {
+		m.Set(d.key, d.value)
+	}
+	m.Get("mercury")
+	require.Equal(t, []int{2, 3, 4, 5, 6, 7, 8, 1}, m.Drain())
+	require.Equal(t, []string{"venus", "earth", "mars", "jupiter", "saturn", "uranus", "neptune", "mercury"}, evicted)
+	require.Zero(t, m.Len())
+	require.Zero(t, m.Size())
+	require.Empty(t, m.Drain())
+
+	m.Set("pluto", 9)
+	v, ok := m.Get("pluto")
+	require.True(t, ok)
+	require.Equal(t, 9, v)
+}
+
+func TestMap_SetOnEvict(t *testing.T) {
+	clk := newFakeClock(t)
+	var evicted []string
+	onEvict := func(k string, _ int) { evicted = append(evicted, k) }
+	var m lru.Map[string, int]
+	m.SetOnEvict(onEvict)
+	for _, d := range td {
+		m.Set(d.key, d.value)
+	}
+	// explicit deletion and replacement are not evictions
+	m.Delete("neptune")
+	m.Set("uranus", 42)
+	require.Empty(t, evicted)
+
+	m.DeleteLRU()
+	require.Equal(t, []string{"mercury"}, evicted)
+	m.SetWithTTL("pluto", 9, time.Second)
+	clk.advance(time.Minute)
+	m.Get("pluto")
+	require.Equal(t, []string{"mercury", "pluto"}, evicted)
+	for i := range 10 {
+		m.Set(strconv.Itoa(i), i)
+	}
+	evicted = nil
+	m.SetCapacity(16)
+	require.Equal(t, []string{"venus", "earth"}, evicted)
+
+	m.SetOnEvict(nil)
+	m.DeleteLRU()
+	require.Len(t, evicted, 2)
+}
+
+func TestMap_SetCapacity(t *testing.T) {
+	var m lru.Map[int, int]
+	m.SetCapacity(1000)
+	require.Equal(t, 1024, m.Capacity())
+	for i := range 800 {
+		m.Set(i, i)
+	}
+	require.Equal(t, 1024, m.Capacity())
+
+	// shrink
+	m.SetCapacity(100)
+	require.Equal(t, 128, m.Capacity())
+	require.Equal(t, 112, m.Len())
+	i := 800 - 112
+	for k, v := range m.All() {
+		require.Equal(t, i, k)
+		require.Equal(t, i, v)
+		i++
+	}
+	require.Equal(t, 800, i)
+
+	// clamp
+	m.SetCapacity(0)
+	require.Equal(t, 16, m.Capacity())
+	require.Equal(t, 14, m.Len())
+	k, _ := m.LRU()
+	require.Equal(t, 800-14, k)
+
+	// grow
+	m.SetCapacity(64)
+	require.Equal(t, 64, m.Capacity())
+	require.Equal(t, 14, m.Len())
+	i = 800 - 14
+	for k := range m.Keys() {
+		require.Equal(t, i, k)
+		i++
+	}
+	for i := 800 - 14; i < 800; i++ {
+		v, ok := m.Get(i)
+		require.True(t, ok)
+		require.Equal(t, i, v)
+	}
+}
+
+func TestMap_Resize(t *testing.T) {
+	var evicted int
+	m := lru.NewMap[int, int](lru.WithOnEvict(func(int, int) { evicted++ }))
+	for i := range 800 {
+		m.Set(i, i)
+	}
+	require.Zero(t, m.Resize(2048))
+	require.Equal(t, 2048, m.Capacity())
+	n := m.Resize(100)
+	require.Equal(t, 800-112, n)
+	require.Equal(t, evicted, n)
+	require.Equal(t, 128, m.Capacity())
+	k, _ := m.LRU()
+	require.Equal(t, 800-112, k)
+	require.Zero(t, m.Resize(256))
+	require.Equal(t, 112, m.Len())
+
+	// pinned entries are kept
+	m = lru.NewMap[int, int]()
+	for i := range 100 {
+		m.Set(i, i)
+		m.Pin(i)
+	}
+	require.Equal(t, 128, m.Capacity())
+	require.Zero(t, m.Resize(16))
+	require.Equal(t, 128, m.Capacity())
+	require.Equal(t, 100, m.Len())
+	for i := range 90 {
+		m.Unpin(i)
+	}
+	require.Equal(t, 86, m.Resize(16))
+	require.Equal(t, 16, m.Capacity())
+	require.Equal(t, 14, m.Len())
+}
+
+func TestMap_Grow(t *testing.T) {
+	var m lru.Map[int, int]
+	m.Grow(1000)
+	c := m.Capacity()
+	require.Equal(t, 2048, c)
+	for i := range 1000 {
+		m.Set(i, i)
+	}
+	require.Equal(t, c, m.Capacity())
+	// no-op
+	m.Grow(10)
+	require.Equal(t, c, m.Capacity())
+	m.Grow(1000)
+	require.Equal(t, 4096, m.Capacity())
+	require.Panics(t, func() { m.Grow(1 << 62) })
+	require.Panics(t, func() { m.Grow(math.MaxInt) })
+	require.Equal(t, 4096, m.Capacity())
+	i := 0
+	for k := range m.Keys() {
+		require.Equal(t, i, k)
+		i++
+	}
+	require.Equal(t, 1000, i)
+}
+
+func TestWithResizeNotify(t *testing.T) {
+	ch := make(chan lru.ResizeEvent, 100)
+	m := lru.NewMap[int, int](lru.WithResizeNotify(ch), lru.WithHasher(hash.Number[int]()))
+	c := m.Capacity()
+	for i := range 1000 {
+		m.Set(i, i)
+	}
+	require.NotZero(t, len(ch))
+	for len(ch) > 0 {
+		ev := <-ch
+		require.Equal(t, c, ev.OldCapacity)
+		require.Greater(t, ev.NewCapacity, ev.OldCapacity)
+		require.Equal(t, lru.ResizeGrow, ev.Reason)
+		c = ev.NewCapacity
+	}
+	require.Equal(t, m.Capacity(), c)
+
+	for i := range 900 {
+		m.Delete(i)
+	}
+	m.ShrinkToFit()
+	require.Equal(t, lru.ResizeEvent{OldCapacity: c, NewCapacity: m.Capacity(), Reason: lru.ResizeManual}, <-ch)
+	// no change, no event
+	m.Resize(m.Capacity())
+	m.Rehash(hash.Number[int]())
+	require.Zero(t, len(ch))
+
+	// pinned entries: a single event with the actual capacity, or none
+	m.Clear()
+	for i := range 100 {
+		m.Set(i, i)
+		m.Pin(i)
+	}
+	m.Resize(1024)
+	for len(ch) > 0 {
+		<-ch
+	}
+	m.Resize(16)
+	require.Equal(t, []lru.ResizeEvent{{OldCapacity: 1024, NewCapacity: 128, Reason: lru.ResizeManual}}, drain(ch))
+	m.Resize(16)
+	require.Empty(t, drain(ch))
+	require.Equal(t, 128, m.Capacity())
+
+	// full channel: the Map does not block
+	ch = make(chan lru.ResizeEvent)
+	m = lru.NewMap[int, int](lru.WithResizeNotify(ch))
+	for i := range 1000 {
+		m.Set(i, i)
+	}
+	require.Equal(t, 1000, m.Len())
+}
+
+// drain returns the events pending in ch.
+func drain(ch chan lru.ResizeEvent) []lru.ResizeEvent {
+	var evs []lru.ResizeEvent
+	for len(ch) > 0 {
+		evs = append(evs, <-ch)
+	}
+	return evs
+}
+
+func TestMap_ShrinkToFit(t *testing.T) {
+	var m lru.Map[int, int]
+	m.ShrinkToFit()
+	require.Equal(t, 0, m.Capacity())
+	for i := range 10000 {
+		m.Set(i, i)
+	}
+	for i := range 9900 {
+		m.Delete(i)
+	}
+	m.ShrinkToFit()
+	require.Equal(t, 256, m.Capacity())
+	i := 9900
+	for k := range m.Keys() {
+		require.Equal(t, i, k)
+		i++
+	}
+	require.Equal(t, 10000, i)
+	// no-op
+	m.ShrinkToFit()
+	require.Equal(t, 256, m.Capacity())
+
+	for range 99 {
+		m.DeleteLRU()
+	}
+	m.ShrinkToFit()
+	require.Equal(t, 16, m.Capacity())
+	k, _ := m.MRU()
+	require.Equal(t, 9999, k)
+
+	// the max load factor is honored
+	m = lru.Map[int, int]{}
+	m.Init(lru.WithCapacity(4096), lru.WithMaxLoadFactor(0.5))
+	for i := range 100 {
+		m.Set(i, i)
+	}
+	m.ShrinkToFit()
+	require.Equal(t, 512, m.Capacity())
+}
+
+func TestMap_Compact(t *testing.T) {
+	xo := New64S()
+	m := lru.NewMap[int, int](lru.WithCapacity(1024))
+	ref := make(map[int]int)
+	for range 20 {
+		for range 400 {
+			k := xo.IntN(2000)
+			if _, ok := ref[k]; ok {
+				m.Delete(k)
+				delete(ref, k)
+			} else {
+				m.Set(k, k)
+				ref[k] = k
+			}
+		}
+		order := slices.Collect(m.Keys())
+		m.Compact()
+		require.Equal(t, 0, m.Tombstones())
+		require.Equal(t, order, slices.Collect(m.Keys()))
+		require.Equal(t, len(ref), m.Len())
+		for k, v := range ref {
+			got, ok := m.Peek(k)
+			require.True(t, ok, "key %d", k)
+			require.Equal(t, v, got)
+		}
+		require.Equal(t, order, slices.Collect(m.Keys()))
+	}
+}
+
+// TestMap_zeroValue checks that all methods can be called on a zero value Map.
+func TestMap_zeroValue(t *testing.T) {
+	type M = lru.Map[int, int]
+	for _, tt := range []struct {
+		name string
+		f    func(*M)
+	}{
+		{"Len", func(m *M) { require.Zero(t, m.Len()) }},
+		{"Load", func(m *M) { require.Zero(t, m.Load()) }},
+		{"Capacity", func(m *M) { require.Zero(t, m.Capacity()) }},
+		{"Size", func(m *M) { require.Zero(t, m.Size()) }},
+		{"LRU", func(m *M) { k, v := m.LRU(); require.Zero(t, k+v) }},
+		{"MRU", func(m *M) { k, v := m.MRU(); require.Zero(t, k+v) }},
+		{"Victim", func(m *M) { _, _, ok := m.Victim(); require.False(t, ok) }},
+		{"PopMRU", func(m *M) { _, _, ok := m.PopMRU(); require.False(t, ok) }},
+		{"String", func(m *M) { require.Equal(t, "{}", m.String()) }},
+		{"All", func(m *M) {
+			for range m.All() {
+				t.Fail()
+			}
+		}},
+		{"Keys", func(m *M) { require.Empty(t, slices.Collect(m.Keys())) }},
+		{"Values", func(m *M) { require.Empty(t, slices.Collect(m.Values())) }},
+		{"AllMRU", func(m *M) {
+			for range m.AllMRU() {
+				t.Fail()
+			}
+		}},
+		{"KeysMRU", func(m *M) { require.Empty(t, slices.Collect(m.KeysMRU())) }},
+		{"ValuesMRU", func(m *M) { require.Empty(t, slices.Collect(m.ValuesMRU())) }},
+		{"Entries", func(m *M) {
+			for range m.Entries() {
+				t.Fail()
+			}
+		}},
+		{"Ordered", func(m *M) { m.Ordered()(func(int, int, int) bool { t.Fail(); return false }) }},
+		{"Get", func(m *M) { _, ok := m.Get(1); require.False(t, ok) }},
+		{"GetRef", func(m *M) { _, ok := m.GetRef(1); require.False(t, ok) }},
+		{"GetMulti", func(m *M) { _, ok := m.GetMulti([]int{1}); require.False(t, ok[0]) }},
+		{"Peek", func(m *M) { _, ok := m.Peek(1); require.False(t, ok) }},
+		{"Touch", func(m *M) { require.False(t, m.Touch(1)) }},
+		{"AppendKeys", func(m *M) { require.Empty(t, m.AppendKeys(nil)); require.Empty(t, m.AppendValues(nil)) }},
+		{"Delete", func(m *M) { _, ok := m.Delete(1); require.False(t, ok) }},
+		{"DeleteFunc", func(m *M) { require.Zero(t, m.DeleteFunc(func(int, int) bool { return true })) }},
+		{"DeleteLRU", func(m *M) { m.DeleteLRU() }},
+		{"EvictN", func(m *M) { require.Zero(t, m.EvictN(1)) }},
+		{"EvictToSize", func(m *M) { m.EvictToSize(-1) }},
+		{"EvictToLimits", func(m *M) { require.Zero(t, m.EvictToLimits(0, 0)) }},
+		{"Drain", func(m *M) { require.Empty(t, m.Drain()) }},
+		{"Pin", func(m *M) { require.False(t, m.Pin(1)); require.False(t, m.Unpin(1)) }},
+		{"Rehash", func(m *M) { m.Rehash(hash.Number[int]()) }},
+		{"PurgeExpired", func(m *M) { require.Zero(t, m.PurgeExpired()) }},
+		{"AverageProbeLength", func(m *M) { require.Zero(t, m.AverageProbeLength()) }},
+		{"Clear", func(m *M) { m.Clear() }},
+		{"Clone", func(m *M) { require.Zero(t, m.Clone().Len()) }},
+		{"Merge", func(m *M) { m.Merge(new(M)) }},
+		{"Grow", func(m *M) { m.Grow(10) }},
+		{"ShrinkToFit", func(m *M) { m.ShrinkToFit() }},
+		{"Compact", func(m *M) { m.Compact() }},
+		{"Resize", func(m *M) { require.Zero(t, m.Resize(10)) }},
+		{"MarshalJSON", func(m *M) { _, err := m.MarshalJSON(); require.NoError(t, err) }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var m M
+			tt.f(&m)
+			// the Map must still be usable
+			m.Set(1, 1)
+			v, ok := m.Get(1)
+			require.True(t, ok)
+			require.Equal(t, 1, v)
+		})
+	}
+}
+
+func TestMap_Load(t *testing.T) {
+	var m lru.Map[int, int]
+	require.Zero(t, m.Load())
+	m.Init(lru.WithCapacity(64))
+	for i := range 16 {
+		m.Set(i, i)
+	}
+	require.Equal(t, 0.25, m.Load())
+}
+
+func TestMap_String(t *testing.T) {
+	var m lru.Map[string, int]
+	require.Equal(t, "{}", m.String())
+	m.Set("mercury", 1)
+	require.Equal(t, "{mercury:1}", m.String())
+	m.Set("venus", 2)
+	require.Equal(t, "{venus:2, mercury:1}", fmt.Sprintf("%v", &m))
+
+	l := lru.NewMap[int, int](lru.WithHasher(hash.Number[int]()))
+	for i := range 100 {
+		l.Set(i, i)
+	}
+	s := l.String()
+	require.True(t, strings.HasPrefix(s, "{99:99, 98:98, "), s)
+	require.True(t, strings.HasSuffix(s, ", 68:68, ...}"), s)
+}
+
+func TestMap_ProbeHistogram(t *testing.T) {
+	var m lru.Map[int, int]
+	require.Empty(t, m.ProbeHistogram())
+	m.Init(lru.WithCapacity(1024), lru.WithHasher(hash.Number[int]()))
+	for i := range 800 {
+		m.Set(i, i)
+	}
+	h := m.ProbeHistogram()
+	n, sum := 0, 0
+	for d, c := range h {
+		n += c
+		sum += (d + 1) * c
+	}
+	require.Equal(t, 800, n)
+	require.Greater(t, h[0], 400)
+	require.InDelta(t, m.AverageProbeLength(), float64(sum)/800, 1e-9)
+
+	// worst case hasher: everything in the same probe sequence
+	m.Init(lru.WithHasher(func(int) uint64 { return 0 }))
+	for i := range 64 {
+		m.Set(i, i)
+	}
+	require.Equal(t, []int{8, 8, 8, 8, 8, 8, 8, 8}, m.ProbeHistogram())
+}
+
+func TestMap_AverageProbeLength(t *testing.T) {
+	var m lru.Map[int, int]
+	require.Zero(t, m.AverageProbeLength())
+	m.Init(lru.WithCapacity(1024), lru.WithHasher(hash.Number[int]()))
+	for i := range 800 {
+		m.Set(i, i)
+	}
+	require.Equal(t, 800, m.Len())
+	require.Equal(t, 0, m.Tombstones())
+	apl := m.AverageProbeLength()
+	require.GreaterOrEqual(t, apl, 1.0)
+	require.Less(t, apl, 2.0)
+
+	// a constant hasher puts all entries in the same probe sequence.
+	m.Init(lru.WithCapacity(1024), lru.WithHasher(func(int) uint64 { return 0 }))
+	for i := range 80 {
+		m.Set(i, i)
+	}
+	require.Greater(t, m.AverageProbeLength(), 5.0)
+}
+
+// FuzzMap_find stresses lookups with adversarial hashes: keys sharing the same
+// high byte share the same probe sequence and all h2 values only differ in their
+// lowest bit, which is the worst case for matchByte false positives.
+func FuzzMap_find(f *testing.F) {
+	f.Add([]byte{0, 1, 0, 2, 0, 3, 1, 2, 2, 1, 2, 2})
+	f.Add([]byte{0, 0, 0, 1, 0, 0, 0, 1, 1, 0, 2, 1})
+	f.Fuzz(func(t *testing.T, ops []byte) {
+		m := lru.NewMap[uint16, int](lru.WithHasher(func(k uint16) uint64 {
+			return uint64(k>>8)<<7 | uint64(k&1)
+		}))
+		ref := make(map[uint16]int)
+		for i := 0; i+1 < len(ops); i += 2 {
+			// spread keys over a few probe sequences
+			key := uint16(ops[i+1]&0x3)<<8 | uint16(ops[i+1]>>2)
+			switch ops[i] % 3 {
+			case 0:
+				m.Set(key, i)
+				ref[key] = i
+			case 1:
+				_, ok := m.Delete(key)
+				_, rok := ref[key]
+				require.Equal(t, rok, ok)
+				delete(ref, key)
+			case 2:
+				v, ok := m.Get(key)
+				rv, rok := ref[key]
+				require.Equal(t, rok, ok)
+				require.Equal(t, rv, v)
+			}
+		}
+		require.Equal(t, len(ref), m.Len())
+		for k, v := range ref {
+			got, ok := m.Peek(k)
+			require.True(t, ok)
+			require.Equal(t, v, got)
+		}
+	})
+}
+
+func TestMap_Rehash(t *testing.T) {
+	m := lru.NewMap[int, int](lru.WithCapacity(1024), lru.WithHasher(func(int) uint64 { return 0 }))
+	for i := range 200 {
+		m.Set(i, i)
+	}
+	m.Delete(100)
+	keys := slices.Collect(m.Keys())
+	bad := m.AverageProbeLength()
+	require.Greater(t, bad, 5.0)
+
+	m.Rehash(hash.Number[int]())
+	require.Equal(t, 1024, m.Capacity())
+	require.Zero(t, m.Tombstones())
+	require.Equal(t, keys, slices.Collect(m.Keys()))
+	for _, k := range keys {
+		v, ok := m.Peek(k)
+		require.True(t, ok)
+		require.Equal(t, k, v)
+	}
+	require.Less(t, m.AverageProbeLength(), 2.0)
+
+	var e lru.Map[int, int]
+	e.Rehash(hash.Number[int]())
+	e.Set(1, 1)
+	require.Equal(t, 1, e.Len())
+}
+
+func Benchmark_Map_bulkLoad(b *testing.B) {
+	const n = 1 << 20
+	b.Run("Set", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			m := lru.NewMap[int, int](lru.WithHasher(hash.Number[int]()))
+			for i := range n {
+				m.Set(i, i)
+			}
+		}
+	})
+	b.Run("Grow+Set", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			m := lru.NewMap[int, int](lru.WithHasher(hash.Number[int]()))
+			m.Grow(n)
+			for i := range n {
+				m.Set(i, i)
+			}
+		}
+	})
+}
+
 const capacity = 1 << 7
 
 func Benchmark_Map_int_int(b *testing.B) {
@@ -316,3 +1459,171 @@ func New64S() *Xorshift64S {
 func (x *Xorshift64S) IntN(n int) int {
 	return int(x.Uint64()&0x7fffffffffffffff) % n
 }
+
+func TestWithHasherSeed(t *testing.T) {
+	m1 := lru.NewMap[string, int](lru.WithHasherSeed(42))
+	m2 := lru.NewMap[string, int](lru.WithHasherSeed(42))
+	for i := range 100 {
+		m1.Set(strconv.Itoa(i), i)
+		m2.Set(strconv.Itoa(i), i)
+	}
+	require.Equal(t, m1.ProbeHistogram(), m2.ProbeHistogram())
+	for i := range 100 {
+		v, ok := m1.Get(strconv.Itoa(i))
+		require.True(t, ok)
+		require.Equal(t, i, v)
+	}
+	require.Panics(t, func() {
+		lru.NewMap[string, int](lru.WithHasherSeed(42), lru.WithHasher(hash.String()))
+	})
+
+	// keys with strings or interfaces
+	type named struct{ Name string }
+	ms := lru.NewMap[named, int](lru.WithHasherSeed(1))
+	ma := lru.NewMap[any, int](lru.WithHasherSeed(1))
+	for i := range 100 {
+		ms.Set(named{strconv.Itoa(i)}, i)
+		ma.Set(strconv.Itoa(i), i)
+		ma.Set(i, i)
+	}
+	for i := range 100 {
+		v, ok := ms.Get(named{strconv.Itoa(i)})
+		require.True(t, ok)
+		require.Equal(t, i, v)
+		v, ok = ma.Get(strconv.Itoa(i))
+		require.True(t, ok)
+		require.Equal(t, i, v)
+		v, ok = ma.Get(i)
+		require.True(t, ok)
+		require.Equal(t, i, v)
+	}
+	require.Panics(t, func() { lru.NewMap[*int, int](lru.WithHasherSeed(1)) })
+}
+
+func TestSuggestCapacity(t *testing.T) {
+	for _, n := range []int{0, 10, 100, 1000, 5000, 100000} {
+		for _, load := range []float64{0, 0.5, 0.75, 0.875, 1} {
+			c := lru.SuggestCapacity(n, load)
+			m := lru.NewMap[int, int](lru.WithCapacity(c), lru.WithHasher(hash.Number[int]()))
+			require.Equal(t, c, m.Capacity())
+			for i := range n {
+				m.Set(i, i)
+			}
+			require.Equal(t, c, m.Capacity(), "n=%d, load=%g", n, load)
+			if load >= 0.125 && load <= 0.875 {
+				require.LessOrEqual(t, m.Load(), load)
+			}
+		}
+	}
+}
+
+func TestWithMaxLoadFactor(t *testing.T) {
+	for _, tt := range []struct {
+		f    float64
+		n    int
+		want int
+	}{
+		{0, 112, 128},
+		{0, 113, 256},
+		{0.5, 64, 128},
+		{0.5, 65, 256},
+		{0.95, 121, 128},
+		{2, 121, 128},
+		{0.1, 65, 256},
+	} {
+		m := lru.NewMap[int, int](lru.WithMaxLoadFactor(tt.f), lru.WithHasher(hash.Number[int]()))
+		m.Grow(tt.n)
+		require.Equal(t, tt.want, m.Capacity(), "f = %g, n = %d", tt.f, tt.n)
+		for i := range tt.n {
+			m.Set(i, i)
+		}
+		require.Equal(t, tt.want, m.Capacity(), "f = %g, n = %d", tt.f, tt.n)
+	}
+	// a small table can be filled to capacity-2 entries
+	m := lru.NewMap[int, int](lru.WithMaxLoadFactor(0.95), lru.WithHasher(hash.Number[int]()))
+	for i := range 15 {
+		m.Set(i, i)
+	}
+	require.Equal(t, 16, m.Capacity())
+}
+
+func Benchmark_maxLoadFactor(b *testing.B) {
+	const n = 100000
+	for _, f := range []float64{0.7, 0.8, 0.9} {
+		b.Run(strconv.FormatFloat(f, 'f', -1, 64), func(b *testing.B) {
+			m := lru.NewMap[int, int](lru.WithMaxLoadFactor(f), lru.WithHasher(hash.Number[int]()))
+			for i := range n {
+				m.Set(i, i)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// 50% misses
+				m.Get(i % (2 * n))
+			}
+			b.ReportMetric(float64(m.Capacity())/n, "slots/entry")
+		})
+	}
+}
+
+func TestWithGrowthRatio(t *testing.T) {
+	const n = 100000
+	rehashes := func(opts ...lru.Option) (int, int) {
+		m := lru.NewMap[int, int](append(opts, lru.WithHasher(hash.Number[int]()))...)
+		c, r := m.Capacity(), 0
+		for i := range n {
+			m.Set(i, i)
+			if m.Capacity() != c {
+				c = m.Capacity()
+				r++
+			}
+		}
+		return r, c
+	}
+	r2, c2 := rehashes()
+	r, c := rehashes(lru.WithGrowthRatio(1.5))
+	require.Equal(t, r2, r)
+	require.Equal(t, c2, c)
+	r4, c4 := rehashes(lru.WithGrowthRatio(4))
+	require.Less(t, r4, r2)
+	require.GreaterOrEqual(t, c4, c2)
+	r8, _ := rehashes(lru.WithGrowthRatio(5))
+	require.Less(t, r8, r4)
+
+	require.Panics(t, func() { lru.WithGrowthRatio(1) })
+	require.Panics(t, func() { lru.WithGrowthRatio(math.NaN()) })
+	require.Panics(t, func() { lru.WithGrowthRatio(math.Inf(1)) })
+	require.Panics(t, func() { lru.WithGrowthRatio(1e19) })
+	require.NotPanics(t, func() { lru.WithGrowthRatio(1 << 16) })
+}
+
+func TestMap_WithOnEvictReason(t *testing.T) {
+	clk := newFakeClock(t)
+	type removal struct {
+		key    string
+		value  int
+		reason lru.EvictReason
+	}
+	var got []removal
+	m := lru.NewMap[string, int](lru.WithOnEvictReason(func(k string, v int, r lru.EvictReason) {
+		got = append(got, removal{k, v, r})
+	}))
+	for _, d := range td {
+		m.Set(d.key, d.value)
+	}
+	m.Set("earth", 30)
+	m.Delete("mars")
+	m.DeleteLRU()
+	m.SetWithTTL("pluto", 9, time.Second)
+	clk.advance(2 * time.Second)
+	m.Get("pluto")
+	m.DeleteFunc(func(k string, _ int) bool { return k == "venus" })
+	require.Equal(t, []removal{
+		{"earth", 3, lru.ReasonReplaced},
+		{"mars", 4, lru.ReasonManual},
+		{"mercury", 1, lru.ReasonCapacity},
+		{"pluto", 9, lru.ReasonExpired},
+		{"venus", 2, lru.ReasonManual},
+	}, got)
+	require.Equal(t, "expired", lru.ReasonExpired.String())
+	require.Equal(t, "EvictReason(42)", lru.EvictReason(42).String())
+}
@@ -26,21 +26,51 @@
 // http://people.csail.mit.edu/shanir/publications/disc2008_submission_98.pdf
 package lru
 
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/db47h/cache/v2/swiss"
+)
+
 // Map represents a Least Recently Used hash table.
 type Map[K comparable, V any] struct {
 	hash     func(K) uint64
+	sizer    func(V) int64
+	onEvict  func(K, V)
+	onRemove func(K, V, EvictReason) // see WithOnEvictReason
+	stats    *Stats
 	meta     []uint8
 	elms     []element[K, V]
 	capacity int
 	active   int
 	deleted  int
+	pinned   int // number of pinned entries
+	size     int64
+	ttl      time.Duration
+	policy   Policy
+	evictor  Evictor[K] // see WithEvictor
+	resizeCh chan<- ResizeEvent
+	expFirst bool    // see WithExpiredFirst
+	nextExp  int64   // WithExpiredFirst: earliest expiration time, 0 if none
+	heads    []int   // PolicyLFU: most recent entry for each frequency
+	maxLoad  float64 // maximum load factor, see WithMaxLoadFactor
+	growAt   int     // rehash or grow when active+deleted > growAt
+	growBits uint8   // log2 of the growth factor, see WithGrowthRatio
 }
 
 type element[K comparable, V any] struct {
-	key   K
-	value V
-	prev  int
-	next  int
+	key     K
+	value   V
+	prev    int
+	next    int
+	expires int64
+	freq    uint8 // PolicyLFU: access count, PolicyClock: referenced bit
+	pinned  bool
 }
 
 func NewMap[K comparable, V any](opts ...Option) *Map[K, V] {
@@ -51,51 +81,417 @@ func NewMap[K comparable, V any](opts ...Option) *Map[K, V] {
 
 func (m *Map[K, V]) Init(opts ...Option) {
 	o := getOpts[K](opts)
-	m.hash = o.hasher.(func(K) uint64)
+	m.init(&o)
 	m.resize(o.capacity)
 }
 
+// Reset is like [Map.Init] but reuses the Map's backing arrays if the
+// capacity set with [WithCapacity] matches the current capacity, in which case
+// all entries are deleted as with [Map.Clear] and, besides processing the
+// options, Reset does not allocate. Otherwise, new arrays are allocated. This
+// makes Reset suitable for recycling Maps, e.g. with a [sync.Pool].
+func (m *Map[K, V]) Reset(opts ...Option) {
+	o := getOpts[K](opts)
+	if o.capacity == m.capacity {
+		m.Clear()
+		m.init(&o)
+		m.growAt = maxActive(m.capacity, m.maxLoad)
+		return
+	}
+	m.init(&o)
+	m.resize(o.capacity)
+}
+
+// init sets the Map's options.
+func (m *Map[K, V]) init(o *options) {
+	m.hash = o.hasher.(func(K) uint64)
+	m.sizer, m.onEvict, m.onRemove = nil, nil, nil
+	if o.sizer != nil {
+		m.sizer = o.sizer.(func(V) int64)
+	}
+	if o.onEvict != nil {
+		m.onEvict = o.onEvict.(func(K, V))
+	}
+	if o.onRemove != nil {
+		m.onRemove = o.onRemove.(func(K, V, EvictReason))
+	}
+	m.ttl = o.ttl
+	m.resizeCh = o.resizeCh
+	m.expFirst = o.expFirst
+	m.nextExp = 0
+	m.maxLoad = o.maxLoad
+	m.growBits = o.growBits
+	m.policy = o.policy
+	m.evictor = nil
+	if o.evictor != nil {
+		m.evictor = o.evictor.(Evictor[K])
+		m.policy = policyCustom
+	}
+	if m.policy != PolicyLFU {
+		m.heads = nil
+	} else if m.heads == nil {
+		m.heads = make([]int, maxFreq+1)
+	} else {
+		clear(m.heads)
+	}
+	switch {
+	case !o.stats:
+		m.stats = nil
+	case m.stats == nil:
+		m.stats = new(Stats)
+	default:
+		*m.stats = Stats{}
+	}
+	m.size = 0
+	m.pinned = 0
+}
+
+// SetOnEvict sets the eviction callback, replacing the one set with
+// [WithOnEvict], if any. A nil onEvict disables the callback.
+func (m *Map[K, V]) SetOnEvict(onEvict func(K, V)) {
+	if m.capacity == 0 {
+		m.Init()
+	}
+	m.onEvict = onEvict
+}
+
+// Clear deletes all entries from the Map, keeping its current capacity and
+// options.
+func (m *Map[K, V]) Clear() {
+	if m.evictor != nil {
+		for i := m.lru(); i != 0; i = m.elms[i].prev {
+			m.evictor.OnRemove(m.elms[i].key)
+		}
+	}
+	clear(m.meta)
+	clear(m.elms)
+	clear(m.heads)
+	m.active = 0
+	m.deleted = 0
+	m.pinned = 0
+	m.size = 0
+	m.nextExp = 0
+}
+
+// Clone returns a copy of the Map, with the same options and LRU order. Keys
+// and values are copied with a simple assignment, so this is a shallow copy if
+// V is a pointer type.
+func (m *Map[K, V]) Clone() *Map[K, V] {
+	c := *m
+	c.meta = slices.Clone(m.meta)
+	c.elms = slices.Clone(m.elms)
+	c.heads = slices.Clone(m.heads)
+	if m.stats != nil {
+		st := *m.stats
+		c.stats = &st
+	}
+	return &c
+}
+
+// Merge sets all entries of other into m, from least to most recently used.
+// The merged entries end up as the most recently used in m, in the same order
+// as in other. For keys present in both maps, the value from other replaces
+// the value in m. Expired entries from other are skipped, and other entries
+// keep their expiration time.
+func (m *Map[K, V]) Merge(other *Map[K, V]) {
+	if other == m {
+		return
+	}
+	for i := other.lru(); i != 0; i = other.elms[i].prev {
+		it := &other.elms[i]
+		if it.expired() {
+			continue
+		}
+		hash, j := m.find(it.key)
+		m.set(hash, j, it.key, it.value, it.expires)
+	}
+}
+
 // Set sets the value for the given key. It returns the previous value and true
 // if there was already a key with that value, otherwize it returns the zero
 // value of V and false.
+//
+// If a default TTL has been set with [WithDefaultTTL], the entry will expire
+// after that duration.
 func (m *Map[K, V]) Set(key K, value V) (prev V, replaced bool) {
 	hash, i := m.find(key)
+	return m.set(hash, i, key, value, expiry(m.ttl))
+}
+
+// SetMulti sets the values for the given keys, as if Set had been called for
+// each key/value pair in order, and returns the number of new entries. The Map
+// is grown beforehand so that at most one rehash occurs.
+//
+// SetMulti panics if keys and values do not have the same length.
+func (m *Map[K, V]) SetMulti(keys []K, values []V) int {
+	if len(keys) != len(values) {
+		panic("lru: SetMulti: len(keys) != len(values)")
+	}
+	m.Grow(len(keys))
+	n := 0
+	for i, key := range keys {
+		if _, replaced := m.Set(key, values[i]); !replaced {
+			n++
+		}
+	}
+	return n
+}
+
+// SetOrdered sets all key value pairs yielded by pairs, in order, so that the
+// last pair yielded ends up as the most recently used entry. Unlike calling
+// [Map.Set] in a loop, replaced entries are always moved to the front,
+// whatever the eviction policy. With PolicyLFU or a custom [Evictor], entries
+// are ordered by the policy, and SetOrdered is then equivalent to Set.
+//
+// SetOrdered is the inverse of [Map.All]: m.SetOrdered(other.All()) on an empty
+// Map reproduces the recency order of other.
+func (m *Map[K, V]) SetOrdered(pairs func(yield func(K, V) bool)) {
+	for k, v := range pairs {
+		hash, i := m.find(k)
+		_, replaced := m.set(hash, i, k, v, expiry(m.ttl))
+		if replaced && m.policy != PolicyLFU && m.policy != policyCustom {
+			it := &m.elms[i]
+			m.unlink(it)
+			m.toFront(it, i)
+		}
+	}
+}
+
+// set sets the value for key, where hash and i are the results of find(key).
+// expires is the expiration timestamp of the entry, see expiry.
+func (m *Map[K, V]) set(hash uint64, i int, key K, value V, expires int64) (prev V, replaced bool) {
 	if i != 0 {
 		it := &m.elms[i]
-		m.unlink(it)
-		m.toFront(it, i)
-		prev, it.value = it.value, value
-		return prev, true
+		if !it.expired() {
+			m.touch(i)
+			prev, it.value = it.value, value
+			it.expires = expires
+			m.trackExpiry(expires)
+			if m.sizer != nil {
+				m.size += m.sizer(value) - m.sizer(prev)
+			}
+			if m.stats != nil {
+				m.stats.Replacements++
+			}
+			if m.onRemove != nil {
+				m.onRemove(key, prev, ReasonReplaced)
+			}
+			return prev, true
+		}
+		m.evict(i)
 	}
 
-	m.insert(hash, key, value)
+	i = m.insert(hash, key, value)
+	m.elms[i].expires = expires
+	m.link(i)
+	m.trackExpiry(expires)
+	if m.sizer != nil {
+		m.size += m.sizer(value)
+	}
+	if m.stats != nil {
+		m.stats.Insertions++
+	}
 	return prev, false
 }
 
+// Get returns the value for the given key and marks it as the most recently
+// used entry. Expired entries are deleted and reported as missing.
 func (m *Map[K, V]) Get(key K) (V, bool) {
+	_, i := m.find(key)
+	return m.get(i)
+}
+
+// GetRef is like [Map.Get] but returns a pointer to the value stored in the
+// Map, allowing in-place updates.
+//
+// The returned pointer must only be used transiently: it is invalidated by any
+// subsequent call to a method that adds or removes entries, since these may
+// move entries around in the underlying table.
+//
+// If a sizer is set with [WithSizer] and an in-place update changes the size
+// of the value as reported by the sizer, [Map.UpdateSize] must be called
+// afterwards, or [Map.Size] will drift.
+func (m *Map[K, V]) GetRef(key K) (*V, bool) {
+	_, i := m.find(key)
+	p := m.ref(i)
+	return p, p != nil
+}
+
+// UpdateSize updates the total size of the Map after the value for the given
+// key has been updated in place, for example through [Map.GetRef]. oldSize is
+// the size of the value as reported by the sizer before the update. It returns
+// false if the key was not found, in which case nothing is changed. Recency is
+// not updated.
+//
+// UpdateSize does nothing if no sizer is set. It does not evict entries, see
+// [Cache.UpdateSize].
+func (m *Map[K, V]) UpdateSize(key K, oldSize int64) bool {
+	_, i := m.find(key)
+	if i == 0 {
+		return false
+	}
+	m.updateSize(i, oldSize)
+	return true
+}
+
+func (m *Map[K, V]) updateSize(i int, oldSize int64) {
+	if m.sizer != nil {
+		m.size += m.sizer(m.elms[i].value) - oldSize
+	}
+}
+
+// Touch marks the entry for the given key as the most recently used, like
+// [Map.Get] but without returning its value, and returns true if the key was
+// found. Expired entries are deleted and reported as missing. Unlike Get, Touch
+// does not update the hit and miss statistics.
+func (m *Map[K, V]) Touch(key K) bool {
+	_, i := m.find(key)
+	if i == 0 {
+		return false
+	}
+	if m.elms[i].expired() {
+		m.evict(i)
+		return false
+	}
+	m.touch(i)
+	return true
+}
+
+// ErrDoNotCache can be returned by the fn argument of GetWithDefault to return
+// a value to the caller without inserting it.
+var ErrDoNotCache = errors.New("lru: do not cache")
+
+// GetWithDefault returns the value for the given key. On a miss, it calls fn to
+// create a new value and inserts it, unless fn returns an error, in which case
+// the error is returned and the Map is left unchanged. If that error is
+// [ErrDoNotCache], the value returned by fn is returned with a nil error, but
+// is not inserted either.
+//
+// fn must not modify the Map.
+func (m *Map[K, V]) GetWithDefault(key K, fn func(K) (V, error)) (V, error) {
+	hash, i := m.find(key)
+	if i != 0 && !m.elms[i].expired() {
+		v, _ := m.get(i)
+		return v, nil
+	}
+	if m.stats != nil {
+		m.stats.Misses++
+	}
+	v, err := fn(key)
+	if err != nil {
+		if errors.Is(err, ErrDoNotCache) {
+			err = nil
+		}
+		return v, err
+	}
+	m.set(hash, i, key, v, expiry(m.ttl))
+	return v, nil
+}
+
+// GetMulti looks up the given keys and returns their values and found flags in
+// the same order as keys. Found entries are marked as most recently used in
+// the order given, as if Get had been called for each key.
+func (m *Map[K, V]) GetMulti(keys []K) ([]V, []bool) {
+	values := make([]V, len(keys))
+	found := make([]bool, len(keys))
+	for n, key := range keys {
+		_, i := m.find(key)
+		values[n], found[n] = m.get(i)
+	}
+	return values, found
+}
+
+// get returns the value of the entry at index i, where i is the result of
+// find(key).
+func (m *Map[K, V]) get(i int) (V, bool) {
+	if p := m.ref(i); p != nil {
+		return *p, true
+	}
+	var zero V
+	return zero, false
+}
+
+// ref promotes the entry at index i and returns a pointer to its value, where i
+// is the result of find(key). It returns nil if there is no such entry or if it
+// expired.
+func (m *Map[K, V]) ref(i int) *V {
+	if i != 0 {
+		it := &m.elms[i]
+		if !it.expired() {
+			m.touch(i)
+			if m.stats != nil {
+				m.stats.Hits++
+			}
+			return &it.value
+		}
+		m.evict(i)
+	}
+	if m.stats != nil {
+		m.stats.Misses++
+	}
+	return nil
+}
+
+// Peek returns the value for the given key without updating its recency.
+// Expired entries are deleted and reported as missing.
+func (m *Map[K, V]) Peek(key K) (V, bool) {
 	if _, i := m.find(key); i != 0 {
 		it := &m.elms[i]
-		m.unlink(it)
-		m.toFront(it, i)
-		return it.value, true
+		if !it.expired() {
+			return it.value, true
+		}
+		m.evict(i)
 	}
 	var zero V
 	return zero, false
 }
 
 // Delete deletes the given key and returns its value and true if the key was
-// found, otherwise it returns the zero value for V and false.
+// found, otherwise it returns the zero value for V and false. Expired entries
+// are deleted but reported as missing.
 func (m *Map[K, V]) Delete(key K) (V, bool) {
-	if _, i := m.find(key); i != 0 {
-		v := m.elms[i].value
-		m.del(i)
-		return v, true
+	_, i := m.find(key)
+	return m.delete(i)
+}
+
+// Take deletes the given key and returns its value, in a single lookup like
+// [Map.Delete]. Unlike Delete, it counts as a lookup in [Stats]: it is meant
+// for one-shot entries, where the entry is consumed rather than invalidated.
+func (m *Map[K, V]) Take(key K) (V, bool) {
+	_, i := m.find(key)
+	v, ok := m.delete(i)
+	if m.stats != nil {
+		if ok {
+			m.stats.Hits++
+		} else {
+			m.stats.Misses++
+		}
+	}
+	return v, ok
+}
+
+// delete deletes the entry at index i, where i is the result of find(key).
+func (m *Map[K, V]) delete(i int) (V, bool) {
+	if i != 0 {
+		it := &m.elms[i]
+		if !it.expired() {
+			k, v := it.key, it.value
+			m.del(i)
+			if m.onRemove != nil {
+				m.onRemove(k, v, ReasonManual)
+			}
+			return v, true
+		}
+		m.evict(i)
 	}
 	var zero V
 	return zero, false
 }
 
-// All returns an iterator for all keys in the Map, lru first.
+// Keys returns an iterator for all keys in the Map, lru first.
+//
+// Deleting the current entry from the loop body is supported; any other
+// modification of the Map during iteration is not.
 func (m *Map[K, V]) Keys() func(yield func(K) bool) {
 	return func(yield func(K) bool) {
 		for i := m.lru(); i != 0; {
@@ -109,7 +505,10 @@ func (m *Map[K, V]) Keys() func(yield func(K) bool) {
 	}
 }
 
-// All returns an iterator for all values in the Map, lru first.
+// Values returns an iterator for all values in the Map, lru first.
+//
+// Deleting the current entry from the loop body is supported; any other
+// modification of the Map during iteration is not.
 func (m *Map[K, V]) Values() func(yield func(V) bool) {
 	return func(yield func(V) bool) {
 		for i := m.lru(); i != 0; {
@@ -124,6 +523,9 @@ func (m *Map[K, V]) Values() func(yield func(V) bool) {
 }
 
 // All returns an iterator for all key value pairs in the Map, lru first.
+//
+// Deleting the current entry from the loop body is supported; any other
+// modification of the Map during iteration is not.
 func (m *Map[K, V]) All() func(yield func(K, V) bool) {
 	return func(yield func(K, V) bool) {
 		for i := m.lru(); i != 0; {
@@ -137,18 +539,283 @@ func (m *Map[K, V]) All() func(yield func(K, V) bool) {
 	}
 }
 
+// Entries is like [Map.All] but yields a pointer to the value stored in the
+// Map instead of a copy, so that values can be updated in place without a
+// second lookup. Recency is not updated.
+//
+// The pointer is only valid until the loop body returns: it must not be
+// retained, and it must not be used after the current entry has been deleted.
+// As with [Map.GetRef], in-place updates that change the size of the value as
+// reported by the sizer, if any, must be followed by a call to
+// [Map.UpdateSize].
+func (m *Map[K, V]) Entries() func(yield func(K, *V) bool) {
+	return func(yield func(K, *V) bool) {
+		for i := m.lru(); i != 0; {
+			it := &m.elms[i]
+			prev := it.prev
+			if !yield(it.key, &it.value) {
+				break
+			}
+			i = prev
+		}
+	}
+}
+
+// KeysMRU returns an iterator for all keys in the Map, mru first.
+//
+// Like with [Map.Keys], the current entry may be deleted from the loop body.
+func (m *Map[K, V]) KeysMRU() func(yield func(K) bool) {
+	return func(yield func(K) bool) {
+		for i := m.mru(); i != 0; {
+			it := &m.elms[i]
+			next := it.next
+			if !yield(it.key) {
+				break
+			}
+			i = next
+		}
+	}
+}
+
+// ValuesMRU returns an iterator for all values in the Map, mru first.
+//
+// Like with [Map.Values], the current entry may be deleted from the loop body.
+func (m *Map[K, V]) ValuesMRU() func(yield func(V) bool) {
+	return func(yield func(V) bool) {
+		for i := m.mru(); i != 0; {
+			it := &m.elms[i]
+			next := it.next
+			if !yield(it.value) {
+				break
+			}
+			i = next
+		}
+	}
+}
+
+// AllMRU returns an iterator for all key value pairs in the Map, mru first.
+//
+// Like with [Map.All], the current entry may be deleted from the loop body.
+func (m *Map[K, V]) AllMRU() func(yield func(K, V) bool) {
+	return func(yield func(K, V) bool) {
+		for i := m.mru(); i != 0; {
+			it := &m.elms[i]
+			next := it.next
+			if !yield(it.key, it.value) {
+				break
+			}
+			i = next
+		}
+	}
+}
+
+// AppendKeys appends all keys in the Map to dst, mru first, and returns the
+// extended slice. Unlike collecting the keys from [Map.KeysMRU], it does not
+// allocate if dst has enough capacity, so that a buffer can be reused across
+// calls.
+func (m *Map[K, V]) AppendKeys(dst []K) []K {
+	for i := m.mru(); i != 0; i = m.elms[i].next {
+		dst = append(dst, m.elms[i].key)
+	}
+	return dst
+}
+
+// AppendValues appends all values in the Map to dst, mru first, and returns
+// the extended slice. See [Map.AppendKeys].
+func (m *Map[K, V]) AppendValues(dst []V) []V {
+	for i := m.mru(); i != 0; i = m.elms[i].next {
+		dst = append(dst, m.elms[i].value)
+	}
+	return dst
+}
+
+// Ordered returns an iterator for all entries in the Map, mru first, along with
+// their zero based recency rank: the rank of the most recently used entry is 0
+// and that of the least recently used one is Len()-1.
+//
+// Since it yields three values, the iterator cannot be used in a range loop
+// and must be called directly:
+//
+//	m.Ordered()(func(pos int, key K, value V) bool {
+//		// ...
+//		return true
+//	})
+//
+// Like with [Map.All], the current entry may be deleted from the loop body,
+// in which case the ranks of the entries that follow are not adjusted.
+func (m *Map[K, V]) Ordered() func(yield func(pos int, key K, value V) bool) {
+	return func(yield func(int, K, V) bool) {
+		pos := 0
+		for i := m.mru(); i != 0; pos++ {
+			it := &m.elms[i]
+			next := it.next
+			if !yield(pos, it.key, it.value) {
+				break
+			}
+			i = next
+		}
+	}
+}
+
+// SortedKeys returns the keys of all unexpired entries in the Map, sorted
+// according to less. The returned slice is a copy that the caller is free to
+// modify. SortedKeys does not update the recency of entries, nor does it delete
+// expired ones.
+func (m *Map[K, V]) SortedKeys(less func(a, b K) bool) []K {
+	keys := m.liveKeys()
+	sortKeys(keys, less)
+	return keys
+}
+
+// liveKeys returns the keys of all unexpired entries, lru first.
+func (m *Map[K, V]) liveKeys() []K {
+	keys := make([]K, 0, m.active)
+	for i := m.lru(); i != 0; i = m.elms[i].prev {
+		if it := &m.elms[i]; !it.expired() {
+			keys = append(keys, it.key)
+		}
+	}
+	return keys
+}
+
+func sortKeys[K any](keys []K, less func(a, b K) bool) {
+	slices.SortFunc(keys, func(a, b K) int {
+		switch {
+		case less(a, b):
+			return -1
+		case less(b, a):
+			return 1
+		}
+		return 0
+	})
+}
+
+// DeleteFunc deletes all entries for which del returns true and returns the
+// number of entries deleted. Entries are visited in LRU order.
+//
+// The del function may delete the entry it is called with, but must not
+// otherwise modify the Map.
+func (m *Map[K, V]) DeleteFunc(del func(K, V) bool) int {
+	n := 0
+	for i := m.lru(); i != 0; {
+		it := &m.elms[i]
+		prev := it.prev
+		if del(it.key, it.value) {
+			// skip if already deleted by del
+			if m.meta[i]&setMask != 0 {
+				k, v := it.key, it.value
+				m.del(i)
+				if m.onRemove != nil {
+					m.onRemove(k, v, ReasonManual)
+				}
+			}
+			n++
+		}
+		i = prev
+	}
+	return n
+}
+
+// DeleteLRU evicts the least recently used entry and returns its key and value.
+// With [PolicyClock], the evicted entry is the first unreferenced entry found
+// by the clock sweep. Pinned entries are skipped. It returns zero values if the
+// Map is empty or if all its entries are pinned.
 func (m *Map[K, V]) DeleteLRU() (key K, value V) {
-	i := m.lru()
+	i := m.victim()
 	if i == 0 {
 		return
 	}
-	it := &m.elms[i]
-	key = it.key
-	value = it.value
-	m.del(i)
+	return m.evict(i)
+}
+
+// PopMRU deletes the most recently used entry and returns its key and value.
+// Like with [Map.Delete], the eviction callback is not called. Expired entries
+// found at the front of the list are evicted and skipped. PopMRU returns false
+// if the Map is empty.
+func (m *Map[K, V]) PopMRU() (key K, value V, ok bool) {
+	for i := m.mru(); i != 0; i = m.mru() {
+		k := m.elms[i].key
+		if value, ok = m.delete(i); ok {
+			return k, value, true
+		}
+	}
 	return
 }
 
+// EvictN evicts up to n entries as with [Map.DeleteLRU] and returns the number
+// of entries evicted. It is a no-op if n <= 0.
+func (m *Map[K, V]) EvictN(n int) int {
+	n = min(n, m.active-m.pinned)
+	for range n {
+		m.DeleteLRU()
+	}
+	return max(n, 0)
+}
+
+// Drain evicts all entries, least recently used first, and returns their
+// values in that order. Unlike [Map.Clear], the eviction callback set with
+// [WithOnEvict] is called for each entry. Pinned entries are left in the Map.
+func (m *Map[K, V]) Drain() []V {
+	values := make([]V, 0, m.active-m.pinned)
+	for m.active > m.pinned {
+		_, v := m.DeleteLRU()
+		values = append(values, v)
+	}
+	return values
+}
+
+// Victim returns the key and value of the entry that the next call to
+// [Map.DeleteLRU] would evict, without removing it or updating its recency.
+// Unlike [Map.LRU], it takes the eviction policy into account. It returns false
+// if the Map is empty.
+func (m *Map[K, V]) Victim() (key K, value V, ok bool) {
+	i := m.peekVictim()
+	if i == 0 {
+		return
+	}
+	return m.elms[i].key, m.elms[i].value, true
+}
+
+// Pin marks the entry for the given key as pinned and returns true if the key
+// was found. Pinned entries are never evicted by [Map.DeleteLRU] and related
+// methods, which evict the next unpinned candidate instead. They still count
+// in [Map.Len] and can still be deleted with [Map.Delete] or expire.
+//
+// Pinning an entry does not change its recency.
+func (m *Map[K, V]) Pin(key K) bool {
+	return m.setPinned(key, true)
+}
+
+// Unpin unpins the entry for the given key and returns true if the key was
+// found. See [Map.Pin].
+func (m *Map[K, V]) Unpin(key K) bool {
+	return m.setPinned(key, false)
+}
+
+func (m *Map[K, V]) setPinned(key K, pinned bool) bool {
+	_, i := m.find(key)
+	if i == 0 {
+		return false
+	}
+	it := &m.elms[i]
+	if it.expired() {
+		m.evict(i)
+		return false
+	}
+	if it.pinned != pinned {
+		it.pinned = pinned
+		if pinned {
+			m.pinned++
+		} else {
+			m.pinned--
+		}
+	}
+	return true
+}
+
+// Pinned returns the number of pinned entries in the Map.
+func (m *Map[K, V]) Pinned() int { return m.pinned }
+
 func (m *Map[K, V]) LRU() (K, V) {
 	i := m.lru()
 	if i == 0 {
@@ -162,7 +829,7 @@ func (m *Map[K, V]) LRU() (K, V) {
 }
 
 func (m *Map[K, V]) MRU() (K, V) {
-	i := m.elms[0].next
+	i := m.mru()
 	if i == 0 {
 		var (
 			zeroK K
@@ -182,9 +849,198 @@ func (m *Map[K, V]) Load() float64 {
 
 func (m *Map[K, V]) Capacity() int { return m.capacity }
 
+// Len returns the number of entries in the Map. This includes expired entries
+// that have not been removed yet, see [Map.PurgeExpired].
 func (m *Map[K, V]) Len() int { return m.active }
 
-func (m *Map[K, V]) insert(hash uint64, key K, value V) {
+// SetCapacity resizes the Map's backing arrays to the given capacity. Like with
+// [WithCapacity], the capacity is rounded up to the next power of two, with a
+// minimum of 16.
+//
+// If the Map holds more entries than the new capacity can accommodate without
+// growing, least recently used entries are evicted until they fit. The LRU
+// order of the remaining entries is preserved. Pinned entries are never
+// evicted, so the resulting capacity may be larger than requested.
+func (m *Map[K, V]) SetCapacity(capacity int) {
+	m.Resize(capacity)
+}
+
+// Resize is like [Map.SetCapacity] but returns the number of entries evicted
+// to fit the new capacity.
+func (m *Map[K, V]) Resize(capacity int) (dropped int) {
+	if m.capacity == 0 {
+		m.Init()
+	}
+	capacity = roundSizeUp(capacity)
+	dropped = m.EvictN(m.active - maxActive(capacity, m.maxLoad))
+	// pinned entries may not all fit.
+	capacity = capacityFor(m.active, capacity, m.maxLoad)
+	if capacity != m.capacity {
+		m.resizeTo(capacity, ResizeManual)
+	}
+	return dropped
+}
+
+// Grow ensures that the Map can hold n more entries without rehashing. If
+// needed, it resizes the backing arrays once, up front.
+func (m *Map[K, V]) Grow(n int) {
+	if m.capacity == 0 {
+		m.Init()
+	}
+	if n > math.MaxInt-m.active {
+		panic("lru: Grow: capacity overflow")
+	}
+	need := m.active + n
+	if n <= 0 || need+m.deleted <= m.growAt {
+		return
+	}
+	m.resizeTo(capacityFor(need, m.capacity, m.maxLoad), ResizeManual)
+}
+
+// ShrinkToFit reduces the capacity of the Map to the smallest capacity that
+// can hold twice its current number of entries without exceeding the maximum
+// load factor, leaving room for new entries before the next rehash. It does
+// nothing if the Map is already that small.
+func (m *Map[K, V]) ShrinkToFit() {
+	if c := capacityFor(m.active*2, minCapacity, m.maxLoad); c < m.capacity {
+		m.resizeTo(c, ResizeManual)
+	}
+}
+
+// Compact rehashes the Map in place in order to clear tombstones left by
+// deleted entries, which improves lookup performance. Since this would
+// otherwise happen during an insert, calling Compact when idle can help
+// reduce latency spikes. It does nothing if there are no tombstones.
+func (m *Map[K, V]) Compact() {
+	if m.deleted > 0 {
+		m.rehashInPlace()
+	}
+}
+
+// Rehash replaces the hash function of the Map and re-inserts all entries into
+// new backing arrays of the same capacity, preserving their recency order. This
+// also clears all tombstones.
+func (m *Map[K, V]) Rehash(hasher func(K) uint64) {
+	if m.capacity == 0 {
+		m.Init()
+	}
+	m.hash = hasher
+	m.rehash(m.capacity)
+}
+
+// Tombstones returns the number of slots marked as deleted. See [Map.Compact].
+func (m *Map[K, V]) Tombstones() int { return m.deleted }
+
+// AverageProbeLength returns the average number of groups probed in order to
+// find an entry, which is at least 1. A high value is a sign of a poor quality
+// hash function.
+//
+// This runs in O(n) and recomputes the hash of every key, so it is meant for
+// diagnostics only.
+func (m *Map[K, V]) AverageProbeLength() float64 {
+	if m.active == 0 {
+		return 0
+	}
+	n := 0
+	for i := m.lru(); i != 0; i = m.elms[i].prev {
+		n += m.probeLength(i)
+	}
+	return float64(n) / float64(m.active)
+}
+
+// maxStringEntries is the maximum number of entries printed by String.
+const maxStringEntries = 32
+
+// String returns a string representation of the Map in the form
+// {k1:v1, k2:v2, ...}, most recently used entries first. Only the first 32
+// entries are printed. This is meant for debugging only.
+func (m *Map[K, V]) String() string {
+	var sb strings.Builder
+	sb.WriteByte('{')
+	n := 0
+	for k, v := range m.AllMRU() {
+		if n > 0 {
+			sb.WriteString(", ")
+		}
+		if n == maxStringEntries {
+			sb.WriteString("...")
+			break
+		}
+		fmt.Fprintf(&sb, "%v:%v", k, v)
+		n++
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+// ProbeHistogram returns a histogram of probe distances: the element at index i
+// is the number of entries found after probing i groups past the first one. A
+// good hash function yields most entries at distance 0. The returned slice is
+// empty if the Map is empty.
+//
+// Like [Map.AverageProbeLength], this is meant for diagnostics only.
+func (m *Map[K, V]) ProbeHistogram() []int {
+	var h []int
+	for i := m.lru(); i != 0; i = m.elms[i].prev {
+		d := m.probeLength(i) - 1
+		for len(h) <= d {
+			h = append(h, 0)
+		}
+		h[d]++
+	}
+	return h
+}
+
+// probeLength returns the number of groups probed by find in order to get to
+// the entry at index i.
+func (m *Map[K, V]) probeLength(i int) int {
+	p := m.probe(m.hash(m.elms[i].key))
+	n := 1
+	for p.distToIndex(i) >= groupSize {
+		p = p.next()
+		n++
+	}
+	return n
+}
+
+// Size returns the sum of the sizes of all entries in the Map, as reported by
+// the function set with [WithSizer]. It always returns 0 if no sizer has been
+// configured.
+func (m *Map[K, V]) Size() int64 { return m.size }
+
+// EvictToSize deletes least recently used entries until Size() <= max and
+// returns the number of entries evicted.
+//
+// Entries larger than max are evicted like any other, so this always
+// terminates, possibly with an empty Map. Note that without a sizer, Size() is
+// always 0, in which case EvictToSize(-1) can be used to evict all entries.
+//
+// Pinned entries are never evicted, so if they are too large, EvictToSize
+// returns with Size() > max.
+func (m *Map[K, V]) EvictToSize(max int64) int {
+	n := 0
+	for m.size > max && m.active > m.pinned {
+		m.DeleteLRU()
+		n++
+	}
+	return n
+}
+
+// EvictToLimits deletes least recently used entries until both Len() <= maxLen
+// and Size() <= maxBytes, and returns the number of entries evicted. See
+// [Map.EvictToSize].
+func (m *Map[K, V]) EvictToLimits(maxLen int, maxBytes int64) int {
+	n := 0
+	for (m.active > maxLen || m.size > maxBytes) && m.active > m.pinned {
+		m.DeleteLRU()
+		n++
+	}
+	return n
+}
+
+// insert inserts a new entry and returns its index. The entry must then be
+// linked into the LRU list.
+func (m *Map[K, V]) insert(hash uint64, key K, value V) int {
 	if m.needRehashOrGrow() {
 		m.rehashOrGrow()
 		hash = m.hash(key)
@@ -192,8 +1048,8 @@ func (m *Map[K, V]) insert(hash uint64, key K, value V) {
 	var i int
 	{ // manual inline of findFirstNotSet
 		for p := m.probe(hash); ; p = p.next() {
-			if e := newBitset(&m.meta[p.groupIndex()]).matchNotSet(); e != 0 {
-				i = p.elementIndex(e.next())
+			if e := newBitset(&m.meta[p.groupIndex()]).MatchNotSet(); e != 0 {
+				i = p.elementIndex(e.Next())
 				break
 			}
 		}
@@ -203,7 +1059,7 @@ func (m *Map[K, V]) insert(hash uint64, key K, value V) {
 	it := &m.elms[i]
 	it.key = key
 	it.value = value
-	m.toFront(it, i)
+	return i
 }
 
 // find returns the hash for the given key and its index in m.elms. If the key is not found,
@@ -215,31 +1071,70 @@ func (m *Map[K, V]) find(key K) (uint64, int) {
 		m.Init()
 	}
 	hash := m.hash(key)
+	return hash, m.lookup(hash, key)
+}
+
+// lookup returns the index of key in m.elms, or 0 if not found.
+func (m *Map[K, V]) lookup(hash uint64, key K) int {
 	p := m.probe(hash)
 	h2 := h2(hash)
 	for {
 		s := newBitset(&m.meta[p.groupIndex()])
-		for mb := s.matchByte(h2); mb != 0; {
-			i := p.elementIndex(mb.next())
-			// mathcByte can yield false positives in rare edge cases, but this is harmless here.
+		for mb := s.MatchByte(h2); mb != 0; {
+			i := p.elementIndex(mb.Next())
+			// matchByte can yield false positives, but only for a set slot
+			// holding h2^1 that immediately follows a true match in the same
+			// group (the borrow from that match propagates into it). This
+			// happens for about 1 in 128 true matches with a neighbor, and the
+			// key comparison below filters them out.
 			if m.elms[i].key == key {
-				return hash, i
+				return i
 			}
 		}
-		if s.matchEmpty() != 0 {
-			return hash, 0
+		if s.MatchEmpty() != 0 {
+			return 0
 		}
 		p = p.next()
 	}
 }
 
+// evict deletes the entry at index i and calls the eviction callbacks, if any.
+func (m *Map[K, V]) evict(i int) (key K, value V) {
+	it := &m.elms[i]
+	key, value = it.key, it.value
+	reason := ReasonCapacity
+	if it.expired() {
+		reason = ReasonExpired
+	}
+	m.del(i)
+	if m.stats != nil {
+		m.stats.Evictions++
+	}
+	if m.onEvict != nil {
+		m.onEvict(key, value)
+	}
+	if m.onRemove != nil {
+		m.onRemove(key, value, reason)
+	}
+	return key, value
+}
+
 func (m *Map[K, V]) del(i int) {
 	it := &m.elms[i]
-	m.unlink(it)
+	m.detach(i)
+	if m.sizer != nil {
+		m.size -= m.sizer(it.value)
+	}
 	var zeroK K
 	var zeroV V
 	it.key = zeroK
 	it.value = zeroV
+	it.expires = 0
+	it.freq = 0
+	if it.pinned {
+		it.pinned = false
+		m.pinned--
+	}
 
 	m.active--
 	// if there is no probe window around index i that has ever been seen as a full group
@@ -251,9 +1146,9 @@ func (m *Map[K, V]) del(i int) {
 	// neighborhood of P as a full group if:
 	//  - there must be an empty slot both before and after i
 	//  - the sequence of consecitve non empty slots around i must be smaller than groupSize
-	if after := newBitset(&m.meta[i]).matchEmpty(); after != 0 {
-		if before := newBitset(&m.meta[(i-1-groupSize)&(m.capacity-1)+1]).matchEmpty(); before != 0 {
-			if before.firstFromEnd()+after.first() < groupSize {
+	if after := newBitset(&m.meta[i]).MatchEmpty(); after != 0 {
+		if before := newBitset(&m.meta[(i-1-groupSize)&(m.capacity-1)+1]).MatchEmpty(); before != 0 {
+			if before.FirstFromEnd()+after.First() < groupSize {
 				m.setH2(i, empty)
 				return
 			}
@@ -266,6 +1161,7 @@ func (m *Map[K, V]) del(i int) {
 
 func (m *Map[K, V]) resize(sz int) {
 	m.capacity = sz
+	m.growAt = maxActive(sz, m.maxLoad)
 	m.elms = make([]element[K, V], m.capacity+1)
 	m.meta = make([]uint8, m.capacity+1+groupSize-1)
 	m.active = 0
@@ -275,7 +1171,7 @@ func (m *Map[K, V]) resize(sz int) {
 func (m *Map[K, V]) rehashInPlace() {
 	// mark all deleted as empty and all set slots as deleted.
 	for i := 1; i < len(m.meta)-groupSize; i += groupSize {
-		markDeletedAsEmptyAndSetAsDeleted(&m.meta[i])
+		swiss.MarkDeletedAsEmptyAndSetAsDeleted(&m.meta[i])
 	}
 	// replicate meta[1:grogroupSize] to the end of the table
 	copy(m.meta[m.capacity+1:], m.meta[1:groupSize])
@@ -291,8 +1187,8 @@ func (m *Map[K, V]) rehashInPlace() {
 		var target int
 		{ // manual inline of findFirstNotSet
 			for p := m.probe(hash); ; p = p.next() {
-				if e := newBitset(&m.meta[p.groupIndex()]).matchNotSet(); e != 0 {
-					target = p.elementIndex(e.next())
+				if e := newBitset(&m.meta[p.groupIndex()]).MatchNotSet(); e != 0 {
+					target = p.elementIndex(e.Next())
 					break
 				}
 			}
@@ -317,6 +1213,7 @@ func (m *Map[K, V]) rehashInPlace() {
 		i = target
 	}
 	m.deleted = 0
+	m.rebuildHeads()
 }
 
 func (m *Map[K, V]) move(target, i int) {
@@ -329,6 +1226,9 @@ func (m *Map[K, V]) move(target, i int) {
 	var zeroV V
 	s.key = zeroK
 	s.value = zeroV
+	s.expires = 0
+	s.freq = 0
+	s.pinned = false
 }
 
 // swap swaps elements at indices i and j.
@@ -338,6 +1238,9 @@ func (m *Map[K, V]) swap(i, j int) {
 
 	pi.key, pj.key = pj.key, pi.key
 	pi.value, pj.value = pj.value, pi.value
+	pi.expires, pj.expires = pj.expires, pi.expires
+	pi.freq, pj.freq = pj.freq, pi.freq
+	pi.pinned, pj.pinned = pj.pinned, pi.pinned
 
 	if pi.next == j {
 		//       x -> i -> j -> y
@@ -346,8 +1249,8 @@ func (m *Map[K, V]) swap(i, j int) {
 		m.elms[pj.next].prev = i
 		pj.prev = pi.prev
 		pi.next = pj.next
-		pi.next = i
-		pj.prev = j
+		pj.next = i
+		pi.prev = j
 	} else if pj.next == i {
 		//       x -> j -> i -> y
 		// swap: x -> i -> j -> y
@@ -370,8 +1273,8 @@ func (m *Map[K, V]) swap(i, j int) {
 
 func (m *Map[K, V]) findFirstNotSet(hash uint64) int {
 	for p := m.probe(hash); ; p = p.next() {
-		if e := newBitset(&m.meta[p.groupIndex()]).matchNotSet(); e != 0 {
-			return p.elementIndex(e.next())
+		if e := newBitset(&m.meta[p.groupIndex()]).MatchNotSet(); e != 0 {
+			return p.elementIndex(e.Next())
 		}
 	}
 }
@@ -381,28 +1284,84 @@ func (m *Map[K, V]) rehashOrGrow() {
 	// we're using the same tuning parameters than abseil-cpp. See
 	// https://github.com/abseil/abseil-cpp/blob/lts_2024_07_22/absl/container/internal/raw_hash_set.cc#L523
 	//
-	if m.active*32 <= m.capacity*25 {
+	if m.active <= rehashInPlaceMax(m.growAt) {
 		m.rehashInPlace()
 		return
 	}
-	src := m.elms
-
 	// we want to keep ɑ >= 1/2 => capacity *= 2ɑ. roundSizeUp will likely
 	// bring it slightly below 1/2, but this is not a major issue.
-	m.resize(m.capacity << 1)
+	m.resizeTo(m.capacity<<m.growBits, ResizeGrow)
+}
+
+// resizeTo rehashes the Map into backing arrays of the given capacity and
+// reports the change, if any, to the channel set with WithResizeNotify. Should
+// the table grow while rehashing, a single event is sent with the final
+// capacity.
+func (m *Map[K, V]) resizeTo(capacity int, reason ResizeReason) {
+	old, ch := m.capacity, m.resizeCh
+	m.resizeCh = nil
+	m.rehash(capacity)
+	m.resizeCh = ch
+	if ch != nil && m.capacity != old {
+		select {
+		case ch <- ResizeEvent{OldCapacity: old, NewCapacity: m.capacity, Reason: reason}:
+		default:
+		}
+	}
+}
+
+// rehash moves all entries to new backing arrays of the given capacity,
+// preserving their LRU order. The new capacity must be large enough to hold all
+// entries without triggering a rehashOrGrow.
+func (m *Map[K, V]) rehash(capacity int) {
+	src := m.elms
+	m.resize(capacity)
 	for i := src[0].prev; i != 0; {
 		it := &src[i]
-		m.insert(m.hash(it.key), it.key, it.value)
+		j := m.insert(m.hash(it.key), it.key, it.value)
+		e := &m.elms[j]
+		e.expires = it.expires
+		e.freq = it.freq
+		e.pinned = it.pinned
+		m.toFront(e, j)
 		i = it.prev
 	}
+	m.rebuildHeads()
+}
+
+// maxActive returns the maximum number of entries that a Map with the given
+// capacity and maximum load factor can hold without needing to grow.
+func maxActive(capacity int, maxLoad float64) int {
+	// always leave at least 2 free slots. This will force a rehash if there
+	// is only 1 free slot before insert, thus making sure that there is at
+	// least 1 free slot post insert.
+	return min(int(float64(capacity)*maxLoad), capacity-2)
+}
+
+// rehashInPlaceMax returns the maximum number of entries for which a full
+// table is rehashed in place rather than grown, given its growAt threshold.
+func rehashInPlaceMax(growAt int) int {
+	// with the default max load factor of 7/8, this is 25/32 of the
+	// capacity.
+	return growAt * 25 / 28
+}
+
+// capacityFor returns the smallest capacity >= min that can hold n entries
+// without needing to grow. It panics if there is no such capacity.
+func capacityFor(n, min int, maxLoad float64) int {
+	c := roundSizeUp(min)
+	for maxActive(c, maxLoad) < n {
+		if c > math.MaxInt>>1 {
+			panic("lru: capacity overflow")
+		}
+		c <<= 1
+	}
+	return c
 }
 
-// needRehashOrGrow returns true if there are less than 2/16 free slots.
+// needRehashOrGrow returns true if there are not enough free slots left.
 func (m *Map[K, V]) needRehashOrGrow() bool {
-	// for minCapatity 16, rhs is 2. This will force a rehash if there is only 1
-	// free slot before insert, thus making sure that there is at least 1 free
-	// slot post insert.
-	return m.capacity-m.active-m.deleted < m.capacity>>3
+	return m.active+m.deleted > m.growAt
 }
 
 func (m *Map[K, V]) probe(hash uint64) probe {
@@ -447,3 +1406,10 @@ func (m *Map[K, V]) lru() int {
 	}
 	return m.elms[0].prev
 }
+
+func (m *Map[K, V]) mru() int {
+	if len(m.elms) < 1 {
+		return 0
+	}
+	return m.elms[0].next
+}
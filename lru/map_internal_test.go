@@ -0,0 +1,143 @@
+package lru
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMap_swap(t *testing.T) {
+	keys := func(m *Map[int, int]) []int {
+		var ks []int
+		for k, v := range m.AllMRU() {
+			require.Equal(t, k, v)
+			ks = append(ks, k)
+		}
+		// check back links
+		var r []int
+		for k := range m.Keys() {
+			r = append(r, k)
+		}
+		slices.Reverse(r)
+		require.Equal(t, ks, r)
+		return ks
+	}
+	populate := func() *Map[int, int] {
+		var m Map[int, int]
+		for i := range 5 {
+			m.Set(i, i)
+		}
+		return &m
+	}
+
+	// swap moves entries around in the table but must preserve the LRU order.
+	check := func(m *Map[int, int], i, j int) {
+		ki, kj := m.elms[i].key, m.elms[j].key
+		m.swap(i, j)
+		require.Equal(t, kj, m.elms[i].key)
+		require.Equal(t, ki, m.elms[j].key)
+		require.Equal(t, []int{4, 3, 2, 1, 0}, keys(m))
+	}
+
+	// adjacent, i -> j
+	m := populate()
+	i := m.elms[0].next
+	check(m, i, m.elms[i].next)
+
+	// adjacent, j -> i
+	m = populate()
+	j := m.elms[0].next
+	check(m, m.elms[j].next, j)
+
+	// disconnected
+	m = populate()
+	i = m.elms[0].next
+	check(m, i, m.elms[m.elms[i].next].next)
+}
+
+func TestMap_Reset_allocs(t *testing.T) {
+	opts := []Option{WithCapacity(64), WithPolicy(PolicyLFU), WithStats(),
+		WithHasher(func(k int) uint64 { return uint64(k) * 0x9e3779b97f4a7c15 })}
+	m := NewMap[int, int](opts...)
+	elms, meta, heads := &m.elms[0], &m.meta[0], &m.heads[0]
+	fill := func() {
+		for i := range 40 {
+			m.Set(i, i)
+			m.Get(i)
+		}
+	}
+	// the only allocation left is the options struct passed to Option.set.
+	optAllocs := testing.AllocsPerRun(10, func() { getOpts[int](opts) })
+	allocs := testing.AllocsPerRun(10, func() {
+		m.Reset(opts...)
+		fill()
+	})
+	require.Equal(t, optAllocs, allocs)
+	require.Same(t, elms, &m.elms[0])
+	require.Same(t, meta, &m.meta[0])
+	require.Same(t, heads, &m.heads[0])
+	checkTable(t, m)
+}
+
+// FuzzMap_del stresses the empty vs. deleted decision in del, in particular at
+// group boundaries and across the replicated metadata at the end of the table.
+// Keys are hashed so that probe sequences start within a few slots of each
+// other, and wrap around the end of small tables.
+func FuzzMap_del(f *testing.F) {
+	f.Add([]byte{0, 1, 0, 2, 0, 3, 1, 2, 0, 18, 0, 34, 1, 18, 2, 34})
+	f.Add([]byte{0, 240, 0, 241, 0, 242, 0, 243, 0, 244, 0, 245, 0, 246, 0, 247, 1, 243, 2, 247})
+	f.Fuzz(func(t *testing.T, ops []byte) {
+		m := NewMap[uint8, int](WithHasher(func(k uint8) uint64 {
+			return uint64(k>>4)<<7 | uint64(k)
+		}))
+		ref := make(map[uint8]int)
+		for i := 0; i+1 < len(ops); i += 2 {
+			key := ops[i+1]
+			switch ops[i] % 4 {
+			case 0, 1:
+				m.Set(key, i)
+				ref[key] = i
+			case 2:
+				_, ok := m.Delete(key)
+				_, rok := ref[key]
+				require.Equal(t, rok, ok)
+				delete(ref, key)
+			case 3:
+				m.DeleteLRU()
+				ref = make(map[uint8]int, len(ref))
+				for k, v := range m.All() {
+					ref[k] = v
+				}
+			}
+			checkTable(t, m)
+			require.Equal(t, len(ref), m.Len())
+			for k, v := range ref {
+				j := m.lookup(m.hash(k), k)
+				require.NotZero(t, j, "key %d not found", k)
+				require.Equal(t, v, m.elms[j].value)
+			}
+		}
+	})
+}
+
+// checkTable checks the consistency of the control bytes of m with its
+// bookkeeping.
+func checkTable[K comparable, V any](t *testing.T, m *Map[K, V]) {
+	t.Helper()
+	nActive, nDeleted := 0, 0
+	for i := 1; i <= m.capacity; i++ {
+		switch c := m.meta[i]; {
+		case c&setMask != 0:
+			nActive++
+		case c == deleted:
+			nDeleted++
+		default:
+			require.Equal(t, uint8(empty), c, "slot %d", i)
+		}
+	}
+	require.Equal(t, m.active, nActive, "active")
+	require.Equal(t, m.deleted, nDeleted, "tombstones")
+	require.Equal(t, m.meta[1:groupSize], m.meta[m.capacity+1:], "replicated control bytes")
+	require.LessOrEqual(t, m.active+m.deleted, m.growAt+1)
+}
@@ -1,12 +1,18 @@
 package lru
 
 import (
+	"math"
 	"math/bits"
+	"strconv"
+	"time"
 
 	"github.com/db47h/cache/v2/hash"
 )
 
-const minCapacity = 16
+const (
+	minCapacity    = 16
+	defaultMaxLoad = 0.875
+)
 
 type Option interface {
 	set(*options)
@@ -17,8 +23,29 @@ type optFn func(*options)
 func (f optFn) set(o *options) { f(o) }
 
 type options struct {
-	hasher   any
-	capacity int
+	hasher     any
+	seed       *uint64
+	sizer      any
+	onEvict    any
+	onRemove   any
+	batch      any
+	deferred   bool
+	onDemote   any
+	writeBack  bool
+	promoBatch int
+	capacity   int
+	ttl        time.Duration
+	negTTL     time.Duration
+	stats      bool
+	expFirst   bool
+	policy     Policy
+	evictor    any
+	resizeCh   chan<- ResizeEvent
+	sketch     int
+	maxSize    int64
+	maxLoad    float64
+	growth     float64
+	growBits   uint8
 }
 
 func WithCapacity(capacity int) Option {
@@ -27,23 +54,351 @@ func WithCapacity(capacity int) Option {
 	})
 }
 
+// SuggestCapacity returns a capacity to pass to [WithCapacity] so that the Map
+// can hold expectedEntries with a load factor of at most targetLoad, without
+// growing. Since capacities are powers of two, the actual load factor will
+// usually be lower.
+//
+// targetLoad is clamped to [0.125, 0.875], the upper bound being the default
+// maximum load factor. For a Map configured with a lower maximum load factor
+// with [WithMaxLoadFactor], targetLoad should not exceed that factor.
+func SuggestCapacity(expectedEntries int, targetLoad float64) int {
+	targetLoad = min(max(targetLoad, 0.125), defaultMaxLoad)
+	return capacityFor(expectedEntries, minCapacity, targetLoad)
+}
+
 func WithHasher[K comparable](hasher func(K) uint64) Option {
 	return optFn(func(o *options) {
 		o.hasher = hasher
 	})
 }
 
+// WithHasherSeed sets a deterministic hasher for the key type, using
+// [hash.GenericSeeded] with the given seed. Two Maps created with the same seed
+// hash keys identically, across runs. This cannot be combined with
+// [WithHasher]. Like hash.GenericSeeded, the Map constructor panics if the key
+// type contains pointers.
+func WithHasherSeed(seed uint64) Option {
+	return optFn(func(o *options) {
+		o.seed = &seed
+	})
+}
+
+// WithSizer sets the function used to compute the size of values. The Map
+// keeps track of the total size of its entries, see [Map.Size] and
+// [Map.EvictToSize].
+func WithSizer[V any](sizer func(V) int64) Option {
+	return optFn(func(o *options) {
+		o.sizer = sizer
+	})
+}
+
+// WithOnEvict sets a function to be called whenever the Map evicts an entry,
+// either by [Map.DeleteLRU] and related methods or because it expired. It is
+// not called for entries removed with [Map.Delete] or when a value is
+// replaced.
+//
+// The callback is called once the entry has been removed and must not modify
+// the Map.
+func WithOnEvict[K comparable, V any](onEvict func(K, V)) Option {
+	return optFn(func(o *options) {
+		o.onEvict = onEvict
+	})
+}
+
+// EvictReason is the reason why an entry was removed from a Map. See
+// [WithOnEvictReason].
+type EvictReason uint8
+
+const (
+	// ReasonCapacity means that the entry was evicted by [Map.DeleteLRU] or
+	// related methods, usually to make room for new entries.
+	ReasonCapacity EvictReason = iota
+	// ReasonManual means that the entry was deleted with [Map.Delete] or
+	// related methods.
+	ReasonManual
+	// ReasonExpired means that the entry was removed because it expired.
+	ReasonExpired
+	// ReasonReplaced means that the value of the entry was replaced by
+	// [Map.Set] or related methods. The key is still present in the Map.
+	ReasonReplaced
+)
+
+var evictReasons = [...]string{"capacity", "manual", "expired", "replaced"}
+
+func (r EvictReason) String() string {
+	if int(r) < len(evictReasons) {
+		return evictReasons[r]
+	}
+	return "EvictReason(" + strconv.Itoa(int(r)) + ")"
+}
+
+// WithOnEvictReason sets a function to be called whenever an entry or a value
+// is removed from the Map, along with the reason why. Unlike [WithOnEvict], it
+// is also called for entries removed with [Map.Delete] and for replaced
+// values. Both callbacks can be set; they are independent.
+//
+// The callback is called once the entry has been removed and must not modify
+// the Map.
+func WithOnEvictReason[K comparable, V any](onRemove func(K, V, EvictReason)) Option {
+	return optFn(func(o *options) {
+		o.onRemove = onRemove
+	})
+}
+
+// ResizeReason is the reason why the backing arrays of a Map were resized.
+type ResizeReason uint8
+
+const (
+	// ResizeGrow means that the Map grew automatically in order to insert
+	// new entries.
+	ResizeGrow ResizeReason = iota
+	// ResizeManual means that the Map was resized by [Map.Resize],
+	// [Map.Grow], [Map.ShrinkToFit] or related methods.
+	ResizeManual
+)
+
+var resizeReasons = [...]string{"grow", "manual"}
+
+func (r ResizeReason) String() string {
+	if int(r) < len(resizeReasons) {
+		return resizeReasons[r]
+	}
+	return "ResizeReason(" + strconv.Itoa(int(r)) + ")"
+}
+
+// ResizeEvent reports a change in the capacity of a Map. See
+// [WithResizeNotify].
+type ResizeEvent struct {
+	OldCapacity int
+	NewCapacity int
+	Reason      ResizeReason
+}
+
+// WithResizeNotify makes the Map send a [ResizeEvent] on ch whenever the
+// capacity of its backing arrays changes. Rehashing in place, which happens
+// when there are enough tombstones to reclaim, does not change the capacity
+// and is not reported.
+//
+// Sends are non-blocking: events are dropped if ch is not ready, so ch should
+// be buffered.
+func WithResizeNotify(ch chan<- ResizeEvent) Option {
+	return optFn(func(o *options) {
+		o.resizeCh = ch
+	})
+}
+
+// WithDeferredOnEvict makes a [SyncMap] call its eviction callback after
+// releasing its lock instead of while holding it, so that the callback can
+// perform slow operations without blocking other goroutines, or access the
+// SyncMap. Entries evicted by a single operation are passed to the callback in
+// eviction order, but callbacks for different operations may run concurrently.
+//
+// A [Map] has no lock to release and always calls its callback right away.
+func WithDeferredOnEvict() Option {
+	return optFn(func(o *options) {
+		o.deferred = true
+	})
+}
+
+// WithBatchOnEvict sets an eviction callback for a [SyncMap] that is called
+// once per operation with all the entries evicted by that operation, in
+// eviction order, instead of once per entry. Like with [WithDeferredOnEvict],
+// the callback is called after the SyncMap lock has been released.
+//
+// The slices passed to onEvict are not reused by the SyncMap. [NewSyncMap]
+// and [NewSharded] panic if this option is combined with [WithOnEvict]. Other
+// types, which evict entries one at a time, ignore it.
+func WithBatchOnEvict[K comparable, V any](onEvict func(keys []K, values []V)) Option {
+	return optFn(func(o *options) {
+		o.batch = onEvict
+	})
+}
+
+// WithDefaultTTL sets the time to live of entries added with [Map.Set]. A
+// value <= 0 disables expiration, which is the default.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return optFn(func(o *options) {
+		o.ttl = ttl
+	})
+}
+
+// WithExpiredFirst makes [Map.DeleteLRU], and therefore all evictions, evict
+// expired entries first, least recently used first, before falling back to the
+// eviction policy. Without it, expired entries are only evicted when looked
+// up, purged, or when they reach the end of the LRU list.
+//
+// Finding expired entries requires scanning the Map, in O(n), but the Map
+// keeps track of the earliest expiration time so that no scan happens until
+// an entry may have expired.
+func WithExpiredFirst() Option {
+	return optFn(func(o *options) {
+		o.expFirst = true
+	})
+}
+
+// WithStats enables the collection of usage statistics, see [Map.Stats].
+func WithStats() Option {
+	return optFn(func(o *options) {
+		o.stats = true
+	})
+}
+
+// WithPolicy sets the eviction policy. The default is [PolicyLRU].
+func WithPolicy(p Policy) Option {
+	return optFn(func(o *options) {
+		o.policy = p
+	})
+}
+
+// WithEvictor sets a custom eviction policy, overriding the one set with
+// [WithPolicy]. Accessing an entry does not change its position in the Map's
+// own list, so [Map.LRU], [Map.MRU] and the iterators reflect insertion order,
+// while [Map.DeleteLRU] and related methods evict the entry selected by e.
+//
+// e must only be used by a single Map. In particular, a clone of the Map made
+// with [Map.Clone] shares e and must not be used.
+func WithEvictor[K comparable](e Evictor[K]) Option {
+	return optFn(func(o *options) {
+		o.evictor = e
+	})
+}
+
+// WithMaxSize sets the maximum total size of the entries in a [Cache], as
+// computed by the sizer set with [WithSizer]. A value <= 0 means no limit,
+// which is the default.
+//
+// A [Map] does not bound its size by itself, see [Map.EvictToSize].
+func WithMaxSize(max int64) Option {
+	return optFn(func(o *options) {
+		o.maxSize = max
+	})
+}
+
+// WithNegativeTTL enables negative caching in [SyncMap.GetWithDefault]: when
+// the function called on a miss returns [ErrNotFound], the miss is remembered
+// for the given duration and further lookups of the same key return
+// ErrNotFound without calling that function again. A ttl <= 0 disables
+// negative caching, which is the default. [Map.GetWithDefault] does not cache
+// misses.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return optFn(func(o *options) {
+		o.negTTL = ttl
+	})
+}
+
+// WithWriteBack makes [Tiered.Set] only write to L1. Modified entries are
+// written to L2 when they are evicted from L1 or by [Tiered.Flush].
+func WithWriteBack() Option {
+	return optFn(func(o *options) {
+		o.writeBack = true
+	})
+}
+
+// WithOnDemote sets a callback called for every entry evicted from the L1 of a
+// [Tiered], after any pending write back.
+func WithOnDemote[K comparable, V any](onDemote func(K, V)) Option {
+	return optFn(func(o *options) {
+		o.onDemote = onDemote
+	})
+}
+
+// WithDeferredPromotion makes [SyncMap.Get] only take a read lock on hits,
+// so that concurrent lookups do not contend on the lock. Instead of moving the
+// entry to the front of the LRU list, hits are queued and applied in batches
+// of up to batch entries, when the queue is full, when
+// [SyncMap.FlushPromotions] is called, or before any operation that takes the
+// exclusive lock, such as Set, a miss in Get, or an eviction. Hit counts in
+// [Stats] are updated at the same time.
+//
+// Since evictions see all queued promotions, they follow the exact LRU order.
+// Methods that only take a read lock, such as [SyncMap.LRU] or
+// [SyncMap.Keys], may however not reflect the latest hits.
+//
+// A batch <= 0 disables deferred promotion, which is the default. The option
+// also applies to the shards of a [Sharded] map.
+func WithDeferredPromotion(batch int) Option {
+	return optFn(func(o *options) {
+		o.promoBatch = batch
+	})
+}
+
+// WithoutRecency disables recency tracking: Get does not reorder entries, which
+// saves some list manipulation on reads for Maps used as plain hash maps. This
+// is the same as WithPolicy(PolicyFIFO): eviction methods still work, but
+// evict entries in insertion order.
+func WithoutRecency() Option {
+	return WithPolicy(PolicyFIFO)
+}
+
+// WithMaxLoadFactor sets the maximum load factor of the hash table, that is the
+// fraction of occupied slots, including tombstones, above which the table is
+// either rehashed in place or grown. Higher values use less memory at the
+// expense of longer probe sequences. f is clamped to [0.5, 0.95]. The default
+// is 0.875.
+func WithMaxLoadFactor(f float64) Option {
+	return optFn(func(o *options) {
+		o.maxLoad = f
+	})
+}
+
+// WithGrowthRatio sets the factor by which the capacity of the hash table is
+// multiplied when it needs to grow. Since capacities are powers of two, r is
+// rounded up to the next power of two. The default is 2. Larger values result
+// in fewer rehashes when the number of entries keeps growing, at the expense
+// of memory usage.
+//
+// WithGrowthRatio panics if r <= 1, if r > 65536, or if r is NaN.
+func WithGrowthRatio(r float64) Option {
+	if !(r > 1 && r <= 1<<16) {
+		panic("lru: growth ratio must be in (1, 65536]")
+	}
+	return optFn(func(o *options) {
+		o.growth = r
+	})
+}
+
+// WithAdmission enables a TinyLFU admission filter backed by a count-min
+// sketch of access frequencies with the given width. When the cache is full, a
+// new key is only admitted if its estimated access frequency is higher than
+// that of the entry that would be evicted to make room for it. Admission is
+// specific to [SLRU].
+func WithAdmission(sketchWidth int) Option {
+	return optFn(func(o *options) {
+		o.sketch = sketchWidth
+	})
+}
+
 func getOpts[K comparable](opts []Option) options {
 	o := options{}
 	for _, op := range opts {
 		op.set(&o)
 	}
-	if o.capacity < minCapacity {
-		o.capacity = minCapacity
+	o.capacity = roundSizeUp(o.capacity)
+	if o.maxLoad == 0 {
+		o.maxLoad = defaultMaxLoad
+	}
+	o.maxLoad = min(max(o.maxLoad, 0.5), 0.95)
+	o.growBits = 1
+	if o.growth > 2 {
+		o.growBits = uint8(bits.Len(uint(math.Ceil(o.growth)) - 1))
+	}
+	if o.seed != nil {
+		if o.hasher != nil {
+			panic("lru: WithHasher and WithHasherSeed are mutually exclusive")
+		}
+		o.hasher = hash.GenericSeeded[K](*o.seed)
 	}
-	o.capacity = 1 << (bits.UintSize - bits.LeadingZeros(uint(o.capacity-1)))
 	if o.hasher == nil {
 		o.hasher = hash.Generic[K]()
 	}
 	return o
 }
+
+// roundSizeUp returns the smallest power of two >= max(sz, minCapacity).
+func roundSizeUp(sz int) int {
+	if sz < minCapacity {
+		sz = minCapacity
+	}
+	return 1 << (bits.UintSize - bits.LeadingZeros(uint(sz-1)))
+}
@@ -0,0 +1,189 @@
+package lru
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// gobEntry is the on-disk format of Map entries.
+type gobEntry[K comparable, V any] struct {
+	Key     K
+	Value   V
+	Expires int64
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// WriteTo implements [io.WriterTo]. It writes all live entries to w in LRU
+// order using [encoding/gob]. K and V must be encodable by gob.
+//
+// Since only keys, values and expiration times are saved, the data can be
+// loaded back with [Map.ReadFrom] into a Map using a different hasher.
+func (m *Map[K, V]) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	enc := gob.NewEncoder(cw)
+	now := nanotime()
+	n := 0
+	for i := m.lru(); i != 0; i = m.elms[i].prev {
+		if it := &m.elms[i]; it.expires == 0 || it.expires > now {
+			n++
+		}
+	}
+	if err := enc.Encode(n); err != nil {
+		return cw.n, err
+	}
+	for i := m.lru(); i != 0; i = m.elms[i].prev {
+		it := &m.elms[i]
+		if it.expires != 0 && it.expires <= now {
+			continue
+		}
+		if err := enc.Encode(gobEntry[K, V]{it.key, it.value, it.expires}); err != nil {
+			return cw.n, err
+		}
+	}
+	return cw.n, nil
+}
+
+// ReadFrom implements [io.ReaderFrom]. It replaces the contents of the Map with
+// entries written by [Map.WriteTo], preserving their LRU order and expiration
+// time. Entries that expired in the meantime are skipped.
+//
+// The returned byte count may be larger than the size of the data written by
+// WriteTo if r does not implement [io.ByteReader].
+func (m *Map[K, V]) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countReader{r: r}
+	dec := gob.NewDecoder(cr)
+	var n int
+	if err := dec.Decode(&n); err != nil {
+		return cr.n, err
+	}
+	m.Clear()
+	// n comes from the stream and cannot be trusted: only pre-size for a
+	// bounded number of entries, the Map grows as needed past that.
+	m.Grow(min(n, maxReadAhead))
+	now := nanotime()
+	for range n {
+		var e gobEntry[K, V]
+		if err := dec.Decode(&e); err != nil {
+			return cr.n, err
+		}
+		if e.Expires != 0 && e.Expires <= now {
+			continue
+		}
+		hash, i := m.find(e.Key)
+		m.set(hash, i, e.Key, e.Value, e.Expires)
+	}
+	return cr.n, nil
+}
+
+// maxReadAhead is the maximum number of entries that ReadFrom makes room for
+// up front.
+const maxReadAhead = 1 << 16
+
+// binEntry is the binary snapshot format of Map entries.
+type binEntry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// checkFixedSize returns an error if K or V are not fixed-size types.
+func checkFixedSize[K comparable, V any]() error {
+	if binary.Size(binEntry[K, V]{}) < 0 {
+		var (
+			k K
+			v V
+		)
+		return fmt.Errorf("lru: snapshot: %T and %T must be fixed-size types", k, v)
+	}
+	return nil
+}
+
+// Snapshot writes a compact binary snapshot of all live entries to w, most
+// recently used first, using [encoding/binary] in little endian order. It
+// starts with the number of entries as a uint64 followed by each key and value.
+// Expiration times are not saved.
+//
+// K and V must be fixed-size types as defined by [binary.Size], like fixed-size
+// integers, floats, or arrays and structs of these. Note that int and uint are
+// not fixed-size. Snapshot returns an error for unsupported types.
+func (m *Map[K, V]) Snapshot(w io.Writer) error {
+	if err := checkFixedSize[K, V](); err != nil {
+		return err
+	}
+	now := nanotime()
+	var n uint64
+	for i := m.mru(); i != 0; i = m.elms[i].next {
+		if it := &m.elms[i]; it.expires == 0 || it.expires > now {
+			n++
+		}
+	}
+	bw := bufio.NewWriter(w)
+	if err := binary.Write(bw, binary.LittleEndian, n); err != nil {
+		return err
+	}
+	for i := m.mru(); i != 0; i = m.elms[i].next {
+		it := &m.elms[i]
+		if it.expires != 0 && it.expires <= now {
+			continue
+		}
+		if err := binary.Write(bw, binary.LittleEndian, binEntry[K, V]{it.key, it.value}); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// LoadSnapshot returns a new Map with the given options, populated with the
+// entries of a snapshot written by [Map.Snapshot] in the same recency order.
+func LoadSnapshot[K comparable, V any](r io.Reader, opts ...Option) (*Map[K, V], error) {
+	if err := checkFixedSize[K, V](); err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(r)
+	var n uint64
+	if err := binary.Read(br, binary.LittleEndian, &n); err != nil {
+		return nil, err
+	}
+	m := NewMap[K, V](opts...)
+	var es []binEntry[K, V]
+	for range n {
+		var e binEntry[K, V]
+		if err := binary.Read(br, binary.LittleEndian, &e); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		es = append(es, e)
+	}
+	m.Grow(len(es))
+	for _, e := range slices.Backward(es) {
+		m.Set(e.Key, e.Value)
+	}
+	return m, nil
+}
@@ -0,0 +1,90 @@
+package lru_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/db47h/cache/v2/hash"
+	"github.com/db47h/cache/v2/lru"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMap_ReadFrom_badCount(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buf).Encode(1<<62))
+	var m lru.Map[int, int]
+	_, err := m.ReadFrom(&buf)
+	require.ErrorIs(t, err, io.EOF)
+	require.Zero(t, m.Len())
+}
+
+func TestMap_WriteTo(t *testing.T) {
+	clk := newFakeClock(t)
+	m := lru.NewMap[string, []byte](lru.WithHasher(hash.String()))
+	for i := range 10000 {
+		k := strconv.Itoa(i)
+		if i%1000 == 0 {
+			m.SetWithTTL(k, []byte(k), time.Duration(i+1)*time.Second)
+		} else {
+			m.Set(k, []byte(k))
+		}
+	}
+	// shuffle recency a bit
+	for i := 0; i < 10000; i += 7 {
+		m.Get(strconv.Itoa(i))
+	}
+
+	var buf bytes.Buffer
+	n, err := m.WriteTo(&buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(buf.Len()), n)
+
+	// load into a non empty map with a different hasher
+	r := lru.NewMap[string, []byte](lru.WithHasher(hash.String()))
+	r.Set("foo", nil)
+	_, err = r.ReadFrom(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, slices.Collect(m.Keys()), slices.Collect(r.Keys()))
+	for k, v := range m.All() {
+		rv, ok := r.Peek(k)
+		require.True(t, ok)
+		require.Equal(t, v, rv)
+	}
+
+	// expiration times are preserved
+	clk.advance(2500 * time.Second)
+	require.Equal(t, 3, r.PurgeExpired())
+}
+
+func TestMap_Snapshot(t *testing.T) {
+	m := lru.NewMap[uint64, uint64](lru.WithHasher(hash.Number[uint64]()))
+	for i := range uint64(1000) {
+		m.Set(i, i*i)
+	}
+	for i := uint64(0); i < 1000; i += 3 {
+		m.Get(i)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, m.Snapshot(&buf))
+	require.Equal(t, 8+1000*16, buf.Len())
+
+	r, err := lru.LoadSnapshot[uint64, uint64](bytes.NewReader(buf.Bytes()), lru.WithHasher(hash.Number[uint64]()))
+	require.NoError(t, err)
+	require.Equal(t, slices.Collect(m.Keys()), slices.Collect(r.Keys()))
+	require.Equal(t, slices.Collect(m.Values()), slices.Collect(r.Values()))
+
+	// truncated
+	_, err = lru.LoadSnapshot[uint64, uint64](bytes.NewReader(buf.Bytes()[:100]))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	// unsupported types
+	require.Error(t, lru.NewMap[int, uint64]().Snapshot(&buf))
+	require.Error(t, lru.NewMap[uint64, string]().Snapshot(&buf))
+	_, err = lru.LoadSnapshot[string, uint64](bytes.NewReader(buf.Bytes()))
+	require.Error(t, err)
+}
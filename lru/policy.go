@@ -0,0 +1,251 @@
+package lru
+
+// Policy is an eviction policy. See [WithPolicy].
+//
+// Whatever the policy, entries are kept in a list ordered by eviction priority:
+// [Map.DeleteLRU] always evicts the entry at the LRU end of the list, and the
+// LRU, MRU methods and iterators walk that list.
+type Policy uint8
+
+const (
+	// PolicyLRU evicts the least recently used entry. This is the default.
+	PolicyLRU Policy = iota
+	// PolicyLFU evicts the least frequently used entry. Ties are broken by
+	// recency, the least recently used entry being evicted first. Access
+	// counters saturate at 255.
+	PolicyLFU
+	// PolicyClock approximates LRU with the CLOCK (second chance) algorithm.
+	// Accessing an entry only sets its referenced bit instead of moving it in
+	// the list. On eviction, referenced entries at the LRU end of the list
+	// have their bit cleared and are moved to the front, and the first
+	// unreferenced entry is evicted. This trades some eviction quality for
+	// cheaper reads.
+	PolicyClock
+	// PolicyFIFO evicts the oldest inserted entry. Accessing or updating an
+	// entry does not change its position, so [Map.LRU], [Map.MRU] and the
+	// iterators reflect insertion order rather than access order.
+	PolicyFIFO
+)
+
+// policyCustom is the internal policy used with a custom [Evictor].
+const policyCustom Policy = 255
+
+// Evictor is a custom eviction policy. See [WithEvictor].
+//
+// The Map keeps the Evictor informed of all insertions, accesses and removals
+// of entries, and asks it which entry to evict next. Entries are identified by
+// their key since their location in the Map changes over time.
+type Evictor[K comparable] interface {
+	// OnInsert is called when key is inserted in the Map.
+	OnInsert(key K)
+	// OnAccess is called when the entry for key is accessed or updated.
+	OnAccess(key K)
+	// OnRemove is called when the entry for key is removed from the Map,
+	// whatever the reason.
+	OnRemove(key K)
+	// Victim returns the key of the next entry to evict, without removing it.
+	// The Map then calls OnRemove if it actually evicts the entry. Victim must
+	// return false if and only if it does not track any key.
+	Victim() (key K, ok bool)
+}
+
+const maxFreq = 255
+
+// touch records an access to the entry at index i.
+func (m *Map[K, V]) touch(i int) {
+	switch m.policy {
+	case PolicyLFU:
+		m.lfuTouch(i)
+	case PolicyClock:
+		m.elms[i].freq = 1
+	case PolicyFIFO:
+	case policyCustom:
+		m.evictor.OnAccess(m.elms[i].key)
+	default:
+		it := &m.elms[i]
+		m.unlink(it)
+		m.toFront(it, i)
+	}
+}
+
+// link links a newly inserted entry into the list.
+func (m *Map[K, V]) link(i int) {
+	switch m.policy {
+	case PolicyLFU:
+		m.elms[i].freq = 1
+		m.insertBefore(i, m.heads[1])
+		m.heads[1] = i
+	case policyCustom:
+		m.elms[i].freq = 0
+		m.toFront(&m.elms[i], i)
+		m.evictor.OnInsert(m.elms[i].key)
+	default:
+		m.elms[i].freq = 0
+		m.toFront(&m.elms[i], i)
+	}
+}
+
+// customVictim returns the index of the entry selected by the custom Evictor.
+// It falls back to the least recent unpinned entry if the Evictor returns an
+// unknown or pinned key.
+func (m *Map[K, V]) customVictim() int {
+	if k, ok := m.evictor.Victim(); ok {
+		if i := m.lookup(m.hash(k), k); i != 0 && !m.elms[i].pinned {
+			return i
+		}
+	}
+	return m.unpinned(m.lru())
+}
+
+// unpinned returns the index of the first entry that is not pinned, starting
+// at index i and walking towards more recent entries.
+func (m *Map[K, V]) unpinned(i int) int {
+	for i != 0 && m.elms[i].pinned {
+		i = m.elms[i].prev
+	}
+	return i
+}
+
+// peekVictim returns the index of the entry that victim would return, without
+// modifying the Map.
+func (m *Map[K, V]) peekVictim() int {
+	if m.active == m.pinned {
+		return 0
+	}
+	if m.expFirst {
+		if i, _ := m.expiredVictim(); i != 0 {
+			return i
+		}
+	}
+	if m.policy == policyCustom {
+		return m.customVictim()
+	}
+	i := m.unpinned(m.lru())
+	if m.policy != PolicyClock {
+		return i
+	}
+	for j := i; j != 0; j = m.elms[j].prev {
+		if e := &m.elms[j]; e.freq == 0 && !e.pinned {
+			return j
+		}
+	}
+	// all unpinned entries referenced: the sweep wraps around to the first
+	// unpinned entry.
+	return i
+}
+
+// victim returns the index of the next entry to evict, or 0 if the Map is
+// empty or all entries are pinned.
+func (m *Map[K, V]) victim() int {
+	if m.active == m.pinned {
+		return 0
+	}
+	if m.expFirst {
+		i, next := m.expiredVictim()
+		m.nextExp = next
+		if i != 0 {
+			return i
+		}
+	}
+	if m.policy == policyCustom {
+		return m.customVictim()
+	}
+	if m.policy != PolicyClock {
+		return m.unpinned(m.lru())
+	}
+	// sweep: give referenced entries a second chance and skip pinned ones.
+	// This terminates since bits are cleared along the way and at least one
+	// entry is not pinned.
+	i := m.lru()
+	for m.elms[i].freq != 0 || m.elms[i].pinned {
+		it := &m.elms[i]
+		it.freq = 0
+		m.unlink(it)
+		m.toFront(it, i)
+		i = m.lru()
+	}
+	return i
+}
+
+// detach unlinks the entry at index i from the list before deletion.
+func (m *Map[K, V]) detach(i int) {
+	switch m.policy {
+	case PolicyLFU:
+		m.lfuUnlink(i)
+		return
+	case policyCustom:
+		m.evictor.OnRemove(m.elms[i].key)
+	}
+	m.unlink(&m.elms[i])
+}
+
+// insertBefore links the entry at index i right before entry j, i.e. i becomes
+// more recent than j. If j is 0, i becomes the least recent entry.
+func (m *Map[K, V]) insertBefore(i, j int) {
+	it := &m.elms[i]
+	p := m.elms[j].prev
+	it.prev = p
+	it.next = j
+	m.elms[p].next = i
+	m.elms[j].prev = i
+}
+
+// rebuildHeads recomputes the LFU bucket heads after entries have been moved.
+func (m *Map[K, V]) rebuildHeads() {
+	if m.heads == nil {
+		return
+	}
+	clear(m.heads)
+	// walking from LRU to MRU, the last entry seen for a given frequency is
+	// the most recent.
+	for i := m.lru(); i != 0; i = m.elms[i].prev {
+		m.heads[m.elms[i].freq] = i
+	}
+}
+
+// The LFU implementation keeps a single list, ordered by frequency first, then
+// by recency. heads[f] is the index of the most recent entry with frequency f,
+// which allows O(1) updates.
+
+func (m *Map[K, V]) lfuUnlink(i int) {
+	it := &m.elms[i]
+	if m.heads[it.freq] == i {
+		if n := it.next; n != 0 && m.elms[n].freq == it.freq {
+			m.heads[it.freq] = n
+		} else {
+			m.heads[it.freq] = 0
+		}
+	}
+	m.unlink(it)
+}
+
+func (m *Map[K, V]) lfuTouch(i int) {
+	it := &m.elms[i]
+	f := it.freq
+	if f == maxFreq {
+		// saturated: move to the front of its own bucket.
+		if h := m.heads[f]; h != i {
+			m.lfuUnlink(i)
+			m.insertBefore(i, h)
+			m.heads[f] = i
+		}
+		return
+	}
+	h := m.heads[f+1]
+	if h == 0 && m.heads[f] == i {
+		// no entries with frequency f+1, and i is already the most recent
+		// entry with frequency f: no need to move it.
+		m.heads[f] = 0
+		if n := it.next; n != 0 && m.elms[n].freq == f {
+			m.heads[f] = n
+		}
+	} else {
+		if h == 0 {
+			h = m.heads[f]
+		}
+		m.lfuUnlink(i)
+		m.insertBefore(i, h)
+	}
+	it.freq = f + 1
+	m.heads[f+1] = i
+}
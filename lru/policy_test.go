@@ -0,0 +1,336 @@
+package lru_test
+
+import (
+	"container/list"
+	"slices"
+	"testing"
+
+	"github.com/db47h/cache/v2/hash"
+	"github.com/db47h/cache/v2/lru"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyLFU(t *testing.T) {
+	m := lru.NewMap[string, int](lru.WithPolicy(lru.PolicyLFU))
+	for _, d := range td {
+		m.Set(d.key, d.value)
+	}
+	// same frequency: LRU order
+	k, _ := m.LRU()
+	require.Equal(t, "mercury", k)
+	m.Get("mercury")
+	m.Get("venus")
+	m.Get("mercury")
+	require.Equal(t, []string{"earth", "mars", "jupiter", "saturn", "uranus", "neptune", "venus", "mercury"},
+		slices.Collect(m.Keys()))
+	k, _ = m.DeleteLRU()
+	require.Equal(t, "earth", k)
+	// new entries are evicted before more frequently used ones
+	m.Set("pluto", 9)
+	require.Equal(t, []string{"mars", "jupiter", "saturn", "uranus", "neptune", "pluto", "venus", "mercury"},
+		slices.Collect(m.Keys()))
+}
+
+// lfuModel is a reference LFU implementation.
+type lfuModel struct {
+	tick  int
+	freq  map[int]int
+	last  map[int]int
+	value map[int]int
+}
+
+func (l *lfuModel) access(k int) {
+	l.tick++
+	l.last[k] = l.tick
+	if l.freq[k] < 255 {
+		l.freq[k]++
+	}
+}
+
+func (l *lfuModel) keys() []int {
+	var ks []int
+	for k := range l.freq {
+		ks = append(ks, k)
+	}
+	slices.SortFunc(ks, func(a, b int) int {
+		if l.freq[a] != l.freq[b] {
+			return l.freq[a] - l.freq[b]
+		}
+		return l.last[a] - l.last[b]
+	})
+	return ks
+}
+
+func (l *lfuModel) del(k int) {
+	delete(l.freq, k)
+	delete(l.last, k)
+	delete(l.value, k)
+}
+
+func TestPolicyLFU_model(t *testing.T) {
+	xo := New64S()
+	m := lru.NewMap[int, int](lru.WithPolicy(lru.PolicyLFU), lru.WithHasher(hash.Number[int]()))
+	ref := &lfuModel{freq: map[int]int{}, last: map[int]int{}, value: map[int]int{}}
+	for i := range 200000 {
+		// skewed key distribution so that some keys saturate
+		k := xo.IntN(1 + xo.IntN(500))
+		switch op := xo.IntN(10); {
+		case op < 5:
+			v, ok := m.Get(k)
+			rv, rok := ref.value[k]
+			require.Equal(t, rok, ok)
+			require.Equal(t, rv, v)
+			if ok {
+				ref.access(k)
+			}
+		case op < 8:
+			m.Set(k, i)
+			if _, ok := ref.value[k]; !ok {
+				ref.freq[k] = 0
+			}
+			ref.value[k] = i
+			ref.access(k)
+		case op < 9:
+			m.Delete(k)
+			ref.del(k)
+		default:
+			if k, _ := m.DeleteLRU(); m.Len() < len(ref.value) {
+				require.Equal(t, ref.keys()[0], k)
+				ref.del(k)
+			}
+		}
+		if i%1000 == 0 {
+			require.Equal(t, ref.keys(), slices.Collect(m.Keys()))
+		}
+	}
+	require.Equal(t, ref.keys(), slices.Collect(m.Keys()))
+	m.Compact()
+	require.Equal(t, ref.keys(), slices.Collect(m.Keys()))
+	m.SetCapacity(m.Capacity() * 2)
+	require.Equal(t, ref.keys(), slices.Collect(m.Keys()))
+}
+
+func TestPolicyClock(t *testing.T) {
+	m := lru.NewMap[string, int](lru.WithPolicy(lru.PolicyClock))
+	for _, d := range td {
+		m.Set(d.key, d.value)
+	}
+	// Get does not reorder entries
+	m.Get("mercury")
+	m.Get("earth")
+	require.Equal(t, []string{"mercury", "venus", "earth", "mars", "jupiter", "saturn", "uranus", "neptune"},
+		slices.Collect(m.Keys()))
+	// mercury gets a second chance
+	k, _ := m.DeleteLRU()
+	require.Equal(t, "venus", k)
+	require.Equal(t, []string{"earth", "mars", "jupiter", "saturn", "uranus", "neptune", "mercury"},
+		slices.Collect(m.Keys()))
+	// earth too, and mercury is now unreferenced
+	k, _ = m.DeleteLRU()
+	require.Equal(t, "mars", k)
+	require.Equal(t, []string{"jupiter", "saturn", "uranus", "neptune", "mercury", "earth"},
+		slices.Collect(m.Keys()))
+	// all entries referenced: full sweep
+	for k := range m.Keys() {
+		m.Get(k)
+	}
+	k, _ = m.DeleteLRU()
+	require.Equal(t, "jupiter", k)
+	require.Equal(t, []string{"saturn", "uranus", "neptune", "mercury", "earth"},
+		slices.Collect(m.Keys()))
+}
+
+func Benchmark_policyGet(b *testing.B) {
+	const n = 1 << 16
+	for _, bb := range []struct {
+		name string
+		opt  lru.Option
+	}{
+		{"LRU", lru.WithPolicy(lru.PolicyLRU)},
+		{"LFU", lru.WithPolicy(lru.PolicyLFU)},
+		{"Clock", lru.WithPolicy(lru.PolicyClock)},
+		{"FIFO", lru.WithPolicy(lru.PolicyFIFO)},
+		{"WithoutRecency", lru.WithoutRecency()},
+		{"Evictor", lru.WithEvictor[int](newFIFOEvictor[int]())},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			m := lru.NewMap[int, int](bb.opt, lru.WithCapacity(n*2), lru.WithHasher(hash.Number[int]()))
+			for i := range n {
+				m.Set(i, i)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.Get(i * 7919 & (n - 1))
+			}
+		})
+	}
+}
+
+func TestPolicyFIFO(t *testing.T) {
+	m := lru.NewMap[string, int](lru.WithPolicy(lru.PolicyFIFO))
+	for _, d := range td {
+		m.Set(d.key, d.value)
+	}
+	for range 10 {
+		m.Get("mercury")
+	}
+	m.Set("venus", 42)
+	k, _ := m.MRU()
+	require.Equal(t, "neptune", k)
+	k, _ = m.DeleteLRU()
+	require.Equal(t, "mercury", k)
+	k, v := m.DeleteLRU()
+	require.Equal(t, "venus", k)
+	require.Equal(t, 42, v)
+}
+
+// fifoEvictor is a FIFO eviction policy implemented as an [lru.Evictor].
+type fifoEvictor[K comparable] struct {
+	l     list.List
+	elems map[K]*list.Element
+}
+
+func newFIFOEvictor[K comparable]() *fifoEvictor[K] {
+	return &fifoEvictor[K]{elems: make(map[K]*list.Element)}
+}
+
+func (e *fifoEvictor[K]) OnInsert(key K) { e.elems[key] = e.l.PushBack(key) }
+
+func (e *fifoEvictor[K]) OnAccess(K) {}
+
+func (e *fifoEvictor[K]) OnRemove(key K) {
+	e.l.Remove(e.elems[key])
+	delete(e.elems, key)
+}
+
+func (e *fifoEvictor[K]) Victim() (K, bool) {
+	if f := e.l.Front(); f != nil {
+		return f.Value.(K), true
+	}
+	var zero K
+	return zero, false
+}
+
+func TestWithEvictor(t *testing.T) {
+	e := newFIFOEvictor[int]()
+	m := lru.NewMap[int, int](lru.WithEvictor[int](e), lru.WithHasher(hash.Number[int]()),
+		lru.WithCapacity(8))
+	f := lru.NewMap[int, int](lru.WithPolicy(lru.PolicyFIFO), lru.WithHasher(hash.Number[int]()),
+		lru.WithCapacity(8))
+	xo := New64S()
+	// same behavior as PolicyFIFO, across rehashes.
+	for i := range 10000 {
+		k := xo.IntN(200)
+		switch i % 7 {
+		case 0:
+			_, ok0 := m.Delete(k)
+			_, ok1 := f.Delete(k)
+			require.Equal(t, ok1, ok0)
+		case 1, 2:
+			m.Get(k)
+			f.Get(k)
+		default:
+			m.Set(k, i)
+			f.Set(k, i)
+		}
+		if m.Len() > 100 {
+			k0, v0 := m.DeleteLRU()
+			k1, v1 := f.DeleteLRU()
+			require.Equal(t, k1, k0)
+			require.Equal(t, v1, v0)
+		}
+		require.Equal(t, m.Len(), e.l.Len())
+	}
+	vk, _, ok := m.Victim()
+	require.True(t, ok)
+	require.Equal(t, e.l.Front().Value, vk)
+	m.Clear()
+	require.Zero(t, e.l.Len())
+	_, _, ok = m.Victim()
+	require.False(t, ok)
+}
+
+func TestMap_Victim(t *testing.T) {
+	for _, p := range []lru.Policy{lru.PolicyLRU, lru.PolicyLFU, lru.PolicyClock, lru.PolicyFIFO} {
+		m := lru.NewMap[int, int](lru.WithPolicy(p), lru.WithHasher(hash.Number[int]()))
+		_, _, ok := m.Victim()
+		require.False(t, ok)
+		xo := New64S()
+		for i := range 1000 {
+			k := xo.IntN(100)
+			if _, ok := m.Get(k); !ok {
+				m.Set(k, -k)
+			}
+			if i%3 == 0 {
+				vk, vv, ok := m.Victim()
+				require.True(t, ok)
+				keys := slices.Collect(m.Keys())
+				k, v := m.DeleteLRU()
+				require.Equal(t, k, vk, "policy %d, list %v", p, keys)
+				require.Equal(t, v, vv)
+			}
+		}
+	}
+}
+
+func TestMap_Pin(t *testing.T) {
+	for _, p := range []lru.Policy{lru.PolicyLRU, lru.PolicyLFU, lru.PolicyClock, lru.PolicyFIFO} {
+		m := lru.NewMap[int, int](lru.WithPolicy(p), lru.WithHasher(hash.Number[int]()))
+		xo := New64S()
+		pinned := make(map[int]bool)
+		for i := range 5000 {
+			k := xo.IntN(200)
+			switch i % 5 {
+			case 0:
+				if m.Pin(k) {
+					pinned[k] = true
+				}
+			case 1:
+				if m.Unpin(k) {
+					delete(pinned, k)
+				}
+			case 2:
+				m.Delete(k)
+				delete(pinned, k)
+			default:
+				if _, ok := m.Get(k); !ok {
+					m.Set(k, -k)
+				}
+			}
+			require.Equal(t, len(pinned), m.Pinned(), p)
+			if m.Len() > 100 {
+				vk, _, ok := m.Victim()
+				require.True(t, ok)
+				k, _ := m.DeleteLRU()
+				require.Equal(t, vk, k)
+				require.False(t, pinned[k], p)
+			}
+		}
+		// evict all unpinned entries.
+		m.Drain()
+		require.Equal(t, len(pinned), m.Len())
+		_, _, ok := m.Victim()
+		require.False(t, ok)
+		require.Zero(t, m.EvictN(10))
+		require.Zero(t, m.EvictToSize(-1))
+		for k := range m.Keys() {
+			require.True(t, pinned[k])
+		}
+	}
+}
+
+func TestWithoutRecency(t *testing.T) {
+	m := lru.NewMap[string, int](lru.WithoutRecency())
+	for _, d := range td {
+		m.Set(d.key, d.value)
+	}
+	for _, d := range td {
+		v, ok := m.Get(d.key)
+		require.True(t, ok)
+		require.Equal(t, d.value, v)
+	}
+	keys := slices.Collect(m.Keys())
+	for i, d := range td {
+		require.Equal(t, d.key, keys[i])
+	}
+}
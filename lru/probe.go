@@ -1,32 +1,25 @@
 package lru
 
+import "github.com/db47h/cache/v2/swiss"
+
+// probe adapts [swiss.Probe] to the 1-indexed backing arrays of a Map.
 type probe struct {
-	offset int
-	acc    int
-	mask   int
+	swiss.Probe
 }
 
 // newProbe returns a [probe] for the given hash.
+//
+// capacity must be a power of two (see [roundSizeUp] and [capacityFor]), in
+// which case masking the low bits of hash maps it uniformly to [0, capacity).
 func newProbe(hash uint, capacity int) probe {
-	mask := capacity - 1
-	return probe{offset: int(hash) & mask, mask: mask}
+	return probe{swiss.NewProbe(hash, capacity)}
 }
 
 // next returns the next probe position using quadratic probing.
-func (p probe) next() probe {
-	p.acc += groupSize
-	p.offset += p.acc
-	p.offset &= p.mask
-	return p
-}
+func (p probe) next() probe { return probe{p.Next()} }
 
-func (p probe) prev() probe {
-	p.offset -= p.acc
-	p.offset &= p.mask
-	p.acc -= groupSize
-	return p
-}
+func (p probe) prev() probe { return probe{p.Prev()} }
 
-func (p probe) groupIndex() int        { return p.offset + 1 } // backing array is 1 indexed
-func (p probe) elementIndex(e int) int { return (p.offset+e)&p.mask + 1 }
-func (p probe) distToIndex(i int) int  { return (i - 1 - p.offset) & p.mask }
+func (p probe) groupIndex() int        { return p.Offset() + 1 } // backing array is 1 indexed
+func (p probe) elementIndex(e int) int { return p.Index(e) + 1 }
+func (p probe) distToIndex(i int) int  { return p.Dist(i - 1) }
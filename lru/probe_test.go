@@ -1,7 +1,9 @@
 package lru
 
 import (
+	"math"
 	"math/rand/v2"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -18,7 +20,7 @@ func Test_probe(t *testing.T) {
 			p0 := p
 			p = p.next()
 			prev := p.prev()
-			require.Equal(t, p0.offset, prev.offset)
+			require.Equal(t, p0.Offset(), prev.Offset())
 			next := prev.next()
 			require.Equal(t, p, next)
 		}
@@ -37,3 +39,29 @@ func Test_probe(t *testing.T) {
 		}
 	}
 }
+
+// Test_probe_distribution checks that probe start positions are uniformly
+// distributed over [1, capacity] for the capacities actually picked by the
+// Map, including those resulting from non power of two requests.
+func Test_probe_distribution(t *testing.T) {
+	for _, n := range []int{10, 100, 1000, 3000, 5000} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			sz := capacityFor(n, minCapacity, defaultMaxLoad)
+			require.Zero(t, sz&(sz-1), "capacity %d is not a power of two", sz)
+			buckets := make([]int, sz+1)
+			const mean = 1000
+			for range sz * mean {
+				p := newProbe(h1(rand.Uint64()), sz)
+				buckets[p.groupIndex()]++
+			}
+			require.Zero(t, buckets[0])
+			sum2 := .0
+			for _, count := range buckets[1:] {
+				sum2 += float64(count) * float64(count)
+			}
+			sd := math.Sqrt(sum2/float64(sz) - mean*mean)
+			// Same lenient bound as Test_h1_h2.
+			require.Less(t, sd, mean*.1)
+		})
+	}
+}
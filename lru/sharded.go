@@ -0,0 +1,154 @@
+package lru
+
+import (
+	"math/bits"
+	"slices"
+)
+
+// Sharded is a concurrent Map split into a number of independently locked
+// shards in order to reduce lock contention.
+//
+// Keys are hashed once: the high bits of the hash select the shard and the
+// full hash is then used for placement within that shard. Since a Map only uses
+// the low bits of the hash for placement, this does not introduce any
+// correlation as long as shard capacities stay below 2^(57-log2(shards)).
+//
+// Note that each shard maintains its own LRU list, so LRU ordering is only
+// guaranteed within a shard.
+type Sharded[K comparable, V any] struct {
+	hash   func(K) uint64
+	shift  uint
+	shards []SyncMap[K, V]
+}
+
+// NewSharded returns a new Sharded Map. The number of shards is rounded up to
+// the next power of two and the capacity set with [WithCapacity] is split
+// evenly across shards, with a minimum of 16 per shard. All other options apply
+// to every shard, including those specific to [SyncMap] such as
+// [WithDeferredOnEvict] or [WithDeferredPromotion].
+func NewSharded[K comparable, V any](shards int, opts ...Option) *Sharded[K, V] {
+	if shards < 1 {
+		shards = 1
+	}
+	n := bits.Len(uint(shards - 1))
+	shards = 1 << n
+	o := getOpts[K](opts)
+	hash := o.hasher.(func(K) uint64)
+	opts = append(slices.Clip(opts), WithCapacity(o.capacity/shards), optFn(func(o *options) {
+		// use the resolved hasher, seeded or not, for all shards.
+		o.hasher, o.seed = hash, nil
+	}))
+
+	s := &Sharded[K, V]{
+		hash:   hash,
+		shift:  uint(64 - n),
+		shards: make([]SyncMap[K, V], shards),
+	}
+	for i := range s.shards {
+		s.shards[i].init(opts)
+	}
+	return s
+}
+
+func (s *Sharded[K, V]) shard(hash uint64) *SyncMap[K, V] {
+	// shift by 64 yields 0 with a single shard.
+	return &s.shards[hash>>s.shift]
+}
+
+// Set sets the value for the given key. See [Map.Set].
+func (s *Sharded[K, V]) Set(key K, value V) (prev V, replaced bool) {
+	hash := s.hash(key)
+	sh := s.shard(hash)
+	sh.lock()
+	m := &sh.m
+	prev, replaced = m.set(hash, m.lookup(hash, key), key, value, expiry(m.ttl))
+	sh.unlock()
+	return
+}
+
+// Get returns the value for the given key. See [SyncMap.Get].
+func (s *Sharded[K, V]) Get(key K) (value V, ok bool) {
+	hash := s.hash(key)
+	sh := s.shard(hash)
+	if sh.promo != nil {
+		sh.mu.RLock()
+		if value, ok = sh.getDeferredRLocked(hash, key); ok {
+			return value, ok
+		}
+	}
+	sh.lock()
+	value, ok = sh.m.get(sh.m.lookup(hash, key))
+	sh.unlock()
+	return
+}
+
+// Delete deletes the given key. See [Map.Delete].
+func (s *Sharded[K, V]) Delete(key K) (value V, ok bool) {
+	hash := s.hash(key)
+	sh := s.shard(hash)
+	sh.lock()
+	value, ok = sh.m.delete(sh.m.lookup(hash, key))
+	sh.unlock()
+	return
+}
+
+// Len returns the total number of entries in all shards.
+func (s *Sharded[K, V]) Len() int {
+	n := 0
+	for i := range s.shards {
+		n += s.shards[i].Len()
+	}
+	return n
+}
+
+// Capacity returns the total capacity of all shards.
+func (s *Sharded[K, V]) Capacity() int {
+	n := 0
+	for i := range s.shards {
+		n += s.shards[i].Capacity()
+	}
+	return n
+}
+
+// Load returns the overall load factor.
+func (s *Sharded[K, V]) Load() float64 {
+	var active, capacity int
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		active += sh.m.active
+		capacity += sh.m.capacity
+		sh.mu.Unlock()
+	}
+	if capacity == 0 {
+		return 0
+	}
+	return float64(active) / float64(capacity)
+}
+
+// Stats returns the sum of the usage statistics of all shards.
+func (s *Sharded[K, V]) Stats() Stats {
+	var st Stats
+	for i := range s.shards {
+		st.add(s.shards[i].Stats())
+	}
+	return st
+}
+
+// ResetStats resets the usage statistics of all shards.
+func (s *Sharded[K, V]) ResetStats() {
+	for i := range s.shards {
+		s.shards[i].ResetStats()
+	}
+}
+
+// FlushPromotions applies the promotions queued by Get in all shards. See
+// [SyncMap.FlushPromotions].
+func (s *Sharded[K, V]) FlushPromotions() {
+	for i := range s.shards {
+		s.shards[i].FlushPromotions()
+	}
+}
+
+// Shards returns the number of shards.
+func (s *Sharded[K, V]) Shards() int { return len(s.shards) }
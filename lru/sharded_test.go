@@ -0,0 +1,96 @@
+package lru_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/db47h/cache/v2/hash"
+	"github.com/db47h/cache/v2/lru"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSharded(t *testing.T) {
+	s := lru.NewSharded[int, int](6, lru.WithCapacity(1000))
+	require.Equal(t, 8, s.Shards())
+	require.Equal(t, 1024, s.Capacity())
+
+	s = lru.NewSharded[int, int](0)
+	require.Equal(t, 1, s.Shards())
+	require.Equal(t, 16, s.Capacity())
+
+	// minimum capacity per shard
+	s = lru.NewSharded[int, int](4, lru.WithCapacity(32))
+	require.Equal(t, 64, s.Capacity())
+
+	s = lru.NewSharded[int, int](4, lru.WithHasherSeed(42))
+	for i := range 100 {
+		s.Set(i, i)
+	}
+	for i := range 100 {
+		v, ok := s.Get(i)
+		require.True(t, ok)
+		require.Equal(t, i, v)
+	}
+}
+
+func TestSharded(t *testing.T) {
+	const (
+		workers = 8
+		n       = 10000
+	)
+	s := lru.NewSharded[int, int](workers, lru.WithHasher(hash.Number[int]()))
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w; i < n; i += workers {
+				s.Set(i, i)
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, n, s.Len())
+	for i := range n {
+		v, ok := s.Get(i)
+		require.True(t, ok)
+		require.Equal(t, i, v)
+	}
+	for i := 0; i < n; i += 2 {
+		_, ok := s.Delete(i)
+		require.True(t, ok)
+	}
+	require.Equal(t, n/2, s.Len())
+	require.InDelta(t, float64(n/2)/float64(s.Capacity()), s.Load(), 1e-9)
+}
+
+func TestSharded_syncMapOptions(t *testing.T) {
+	clk := newFakeClock(t)
+	var (
+		s       *lru.Sharded[int, int]
+		evicted []int
+	)
+	s = lru.NewSharded[int, int](4, lru.WithHasher(hash.Number[int]()),
+		lru.WithDefaultTTL(time.Second), lru.WithStats(),
+		lru.WithDeferredOnEvict(), lru.WithDeferredPromotion(16),
+		lru.WithOnEvict(func(k, _ int) {
+			// would deadlock if called with the shard lock held
+			s.Get(k)
+			evicted = append(evicted, k)
+		}))
+	for i := range 8 {
+		s.Set(i, i)
+	}
+	for i := range 8 {
+		_, ok := s.Get(i)
+		require.True(t, ok)
+	}
+	s.FlushPromotions()
+	require.Equal(t, uint64(8), s.Stats().Hits)
+
+	clk.advance(2 * time.Second)
+	_, ok := s.Get(3)
+	require.False(t, ok)
+	require.Equal(t, []int{3}, evicted)
+}
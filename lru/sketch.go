@@ -0,0 +1,73 @@
+package lru
+
+import "math/bits"
+
+const (
+	sketchDepth   = 4
+	sketchMaxFreq = 15
+)
+
+// sketch is a count-min sketch of access frequencies. Counters saturate at
+// 15 and are halved every 10*width increments so that the sketch adapts to
+// changing workloads.
+type sketch struct {
+	rows    [sketchDepth][]uint8
+	mask    uint64
+	count   int
+	resetAt int
+}
+
+func newSketch(width int) *sketch {
+	width = roundSizeUp(width)
+	s := &sketch{
+		mask:    uint64(width - 1),
+		resetAt: 10 * width,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// index returns the counter index in row r for the given key hash.
+func (s *sketch) index(hash uint64, r int) uint64 {
+	return bits.RotateLeft64(hash*0x9e3779b97f4a7c15, r*16) & s.mask
+}
+
+// add records an access for the given key hash.
+func (s *sketch) add(hash uint64) {
+	added := false
+	for r := range s.rows {
+		c := &s.rows[r][s.index(hash, r)]
+		if *c < sketchMaxFreq {
+			*c++
+			added = true
+		}
+	}
+	if added {
+		s.count++
+		if s.count >= s.resetAt {
+			s.age()
+		}
+	}
+}
+
+// estimate returns the estimated access frequency for the given key hash.
+func (s *sketch) estimate(hash uint64) int {
+	f := uint8(sketchMaxFreq)
+	for r := range s.rows {
+		f = min(f, s.rows[r][s.index(hash, r)])
+	}
+	return int(f)
+}
+
+// age halves all counters.
+func (s *sketch) age() {
+	for r := range s.rows {
+		row := s.rows[r]
+		for i := range row {
+			row[i] >>= 1
+		}
+	}
+	s.count >>= 1
+}
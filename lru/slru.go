@@ -0,0 +1,153 @@
+package lru
+
+// SLRU is a segmented LRU cache with a fixed maximum number of entries.
+//
+// New entries are added to a probationary segment, and are moved to a
+// protected segment when accessed again. Entries evicted from the protected
+// segment are moved back to the probationary segment. Eviction victims are
+// always taken from the probationary segment first, which protects frequently
+// used entries from being flushed by scans of entries accessed only once.
+type SLRU[K comparable, V any] struct {
+	probation    Map[K, V]
+	protected    Map[K, V]
+	capacity     int
+	protectedCap int
+	sketch       *sketch // admission filter, see WithAdmission
+}
+
+// NewSLRU returns a new SLRU cache holding at most capacity entries, of which
+// at most capacity*protectedFraction are in the protected segment. Options
+// apply to both segments. The eviction callback set with [WithOnEvict] is only
+// called for entries evicted from the cache, not for entries moved between
+// segments.
+func NewSLRU[K comparable, V any](capacity int, protectedFraction float64, opts ...Option) *SLRU[K, V] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	protectedFraction = min(max(protectedFraction, 0), 1)
+	c := &SLRU[K, V]{
+		capacity:     capacity,
+		protectedCap: int(float64(capacity) * protectedFraction),
+	}
+	c.probation.Init(opts...)
+	c.protected.Init(opts...)
+	// share the same hasher
+	c.protected.hash = c.probation.hash
+	if o := getOpts[K](opts); o.sketch > 0 {
+		c.sketch = newSketch(o.sketch)
+	}
+	return c
+}
+
+// Get returns the value for the given key. Entries found in the probationary
+// segment are moved to the protected segment.
+func (c *SLRU[K, V]) Get(key K) (V, bool) {
+	hash := c.probation.hash(key)
+	if c.sketch != nil {
+		c.sketch.add(hash)
+	}
+	if i := c.protected.lookup(hash, key); i != 0 {
+		return c.protected.get(i)
+	}
+	if i := c.probation.lookup(hash, key); i != 0 && !c.probation.elms[i].expired() {
+		return c.promote(i), true
+	}
+	var zero V
+	return zero, false
+}
+
+// Set sets the value for the given key. New entries are added to the
+// probationary segment, evicting the least recently used entry of that
+// segment if the cache is full.
+//
+// If an admission filter is enabled with [WithAdmission] and the cache is full,
+// new entries may be rejected, in which case Set is a no-op.
+func (c *SLRU[K, V]) Set(key K, value V) (prev V, replaced bool) {
+	hash := c.probation.hash(key)
+	if c.sketch != nil {
+		c.sketch.add(hash)
+	}
+	if i := c.protected.lookup(hash, key); i != 0 {
+		return c.protected.set(hash, i, key, value, expiry(c.protected.ttl))
+	}
+	i := c.probation.lookup(hash, key)
+	if i == 0 && c.Len() >= c.capacity && !c.admit(hash) {
+		return prev, false
+	}
+	prev, replaced = c.probation.set(hash, i, key, value, expiry(c.probation.ttl))
+	for c.Len() > c.capacity {
+		if c.probation.active > 0 {
+			c.probation.DeleteLRU()
+		} else {
+			c.protected.DeleteLRU()
+		}
+	}
+	return prev, replaced
+}
+
+// Delete deletes the given key from the cache.
+func (c *SLRU[K, V]) Delete(key K) (V, bool) {
+	if v, ok := c.protected.Delete(key); ok {
+		return v, ok
+	}
+	return c.probation.Delete(key)
+}
+
+// admit returns true if the key with the given hash should be admitted into the
+// cache, replacing the current eviction victim.
+func (c *SLRU[K, V]) admit(hash uint64) bool {
+	if c.sketch == nil {
+		return true
+	}
+	m := &c.probation
+	if m.active == 0 {
+		m = &c.protected
+	}
+	victim := m.hash(m.elms[m.peekVictim()].key)
+	return c.sketch.estimate(hash) > c.sketch.estimate(victim)
+}
+
+// promote moves the probationary entry at index i to the protected segment
+// and returns its value.
+func (c *SLRU[K, V]) promote(i int) V {
+	it := &c.probation.elms[i]
+	key, value, expires := it.key, it.value, it.expires
+	c.probation.del(i)
+	if c.protectedCap == 0 {
+		// no protected segment, reinsert as probationary
+		c.move(&c.probation, key, value, expires)
+		return value
+	}
+	if c.protected.active >= c.protectedCap {
+		// demote the protected LRU entry.
+		j := c.protected.lru()
+		it := &c.protected.elms[j]
+		k, v, e := it.key, it.value, it.expires
+		c.protected.del(j)
+		c.move(&c.probation, k, v, e)
+	}
+	c.move(&c.protected, key, value, expires)
+	return value
+}
+
+func (c *SLRU[K, V]) move(dst *Map[K, V], key K, value V, expires int64) {
+	hash, i := dst.find(key)
+	dst.set(hash, i, key, value, expires)
+}
+
+// EstimateFrequency returns the estimated access frequency of the given key as
+// recorded by the admission filter, without recording an access. It returns 0
+// for keys never seen or if the admission filter is disabled. Estimates
+// saturate at 15 and are periodically halved.
+func (c *SLRU[K, V]) EstimateFrequency(key K) uint32 {
+	if c.sketch == nil {
+		return 0
+	}
+	return uint32(c.sketch.estimate(c.probation.hash(key)))
+}
+
+// Len returns the number of entries in the cache.
+func (c *SLRU[K, V]) Len() int { return c.probation.active + c.protected.active }
+
+// Capacity returns the maximum number of entries in the cache.
+func (c *SLRU[K, V]) Capacity() int { return c.capacity }
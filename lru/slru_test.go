@@ -0,0 +1,159 @@
+package lru_test
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/db47h/cache/v2/hash"
+	"github.com/db47h/cache/v2/lru"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLRU(t *testing.T) {
+	var evicted int
+	c := lru.NewSLRU[string, int](100, 0.8,
+		lru.WithHasher(hash.String()),
+		lru.WithOnEvict(func(string, int) { evicted++ }))
+
+	c.Set("hot", 42)
+	_, ok := c.Get("hot")
+	require.True(t, ok)
+
+	// flood with one-hit wonders
+	for i := range 1000 {
+		c.Set(strconv.Itoa(i), i)
+	}
+	require.Equal(t, 100, c.Len())
+	require.Equal(t, 901, evicted)
+	v, ok := c.Get("hot")
+	require.True(t, ok)
+	require.Equal(t, 42, v)
+
+	// fill the protected segment, "hot" gets demoted then evicted.
+	for i := 1000; i < 1080; i++ {
+		c.Set(strconv.Itoa(i), i)
+		c.Get(strconv.Itoa(i))
+	}
+	require.Equal(t, 100, c.Len())
+	for i := 2000; i < 2020; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+	_, ok = c.Get("hot")
+	require.False(t, ok)
+	for i := 1000; i < 1080; i++ {
+		_, ok := c.Get(strconv.Itoa(i))
+		require.True(t, ok)
+	}
+
+	v, ok = c.Delete("1000")
+	require.True(t, ok)
+	require.Equal(t, 1000, v)
+	require.Equal(t, 99, c.Len())
+}
+
+func TestSLRU_plainLRU(t *testing.T) {
+	// for comparison, a plain LRU evicts "hot"
+	m := newMap[string, int](100, lru.WithHasher(hash.String()))
+	m.Set("hot", 42)
+	m.Get("hot")
+	for i := range 1000 {
+		m.Set(strconv.Itoa(i), i)
+	}
+	_, ok := m.Get("hot")
+	require.False(t, ok)
+}
+
+func TestSLRU_admission(t *testing.T) {
+	c := lru.NewSLRU[int, int](10, 0.8, lru.WithHasher(hash.Number[int]()), lru.WithAdmission(1024))
+	for i := range 10 {
+		c.Set(i, i)
+		c.Get(i)
+		c.Get(i)
+	}
+	require.Equal(t, 10, c.Len())
+	// one-off keys are rejected
+	for i := 100; i < 200; i++ {
+		c.Set(i, i)
+		_, ok := c.Get(i)
+		require.False(t, ok)
+	}
+	for i := range 10 {
+		_, ok := c.Get(i)
+		require.True(t, ok)
+	}
+	// a key accessed often enough gets in
+	for range 4 {
+		c.Get(1000)
+	}
+	require.Equal(t, uint32(4), c.EstimateFrequency(1000))
+	require.Equal(t, uint32(4), c.EstimateFrequency(1000))
+	require.Equal(t, uint32(0), c.EstimateFrequency(2000))
+	c.Set(1000, 1000)
+	_, ok := c.Get(1000)
+	require.True(t, ok)
+	require.Equal(t, 10, c.Len())
+}
+
+func TestSLRU_EstimateFrequency(t *testing.T) {
+	const width = 16
+	c := lru.NewSLRU[int, int](10, 0.8, lru.WithHasher(hash.Number[int]()), lru.WithAdmission(width))
+	require.Equal(t, uint32(0), c.EstimateFrequency(1))
+	for range 20 {
+		c.Get(1)
+	}
+	// saturated
+	require.Equal(t, uint32(15), c.EstimateFrequency(1))
+	// aging kicks in after 10*width increments
+	for i := 2; i < 200; i++ {
+		c.Get(i)
+	}
+	require.Less(t, c.EstimateFrequency(1), uint32(15))
+
+	c = lru.NewSLRU[int, int](10, 0.8)
+	c.Get(1)
+	require.Equal(t, uint32(0), c.EstimateFrequency(1))
+}
+
+// Benchmark_hitRatio compares the hit ratio of a plain LRU, an SLRU and an SLRU
+// with admission filter on a Zipfian key distribution.
+func Benchmark_hitRatio(b *testing.B) {
+	const (
+		capacity = 1000
+		keys     = 100000
+	)
+	type cache struct {
+		get func(int) (int, bool)
+		set func(int, int)
+	}
+	slru := func(opts ...lru.Option) cache {
+		c := lru.NewSLRU[int, int](capacity, 0.8, append(opts, lru.WithHasher(hash.Number[int]()))...)
+		return cache{c.Get, func(k, v int) { c.Set(k, v) }}
+	}
+	for _, bb := range []struct {
+		name string
+		new  func() cache
+	}{
+		{"LRU", func() cache {
+			m := newMap[int, int](capacity, lru.WithHasher(hash.Number[int]()))
+			return cache{m.Get, m.Set}
+		}},
+		{"SLRU", func() cache { return slru() }},
+		{"TinyLFU", func() cache { return slru(lru.WithAdmission(capacity)) }},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			c := bb.new()
+			z := rand.NewZipf(rand.New(rand.NewSource(42)), 1.01, 1, keys-1)
+			var hits int
+			for i := 0; i < b.N; i++ {
+				k := int(z.Uint64())
+				if _, ok := c.get(k); ok {
+					hits++
+				} else {
+					c.set(k, k)
+				}
+			}
+			b.ReportMetric(float64(hits)/float64(b.N), "hits/op")
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package lru
+
+// Stats holds usage statistics for a Map. See [WithStats].
+type Stats struct {
+	Hits         uint64 // Get calls that found a live entry
+	Misses       uint64 // Get calls that did not find a live entry
+	Insertions   uint64 // new entries added by Set
+	Replacements uint64 // existing entries updated by Set
+	Evictions    uint64 // entries evicted or expired
+	Loads        uint64 // successful loader calls, see SyncMap.GetWithDefault
+	LoadErrors   uint64 // loader calls that returned an error
+}
+
+// HitRatio returns Hits / (Hits + Misses), or 0 if there were no lookups.
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+func (s *Stats) add(o Stats) {
+	s.Hits += o.Hits
+	s.Misses += o.Misses
+	s.Insertions += o.Insertions
+	s.Replacements += o.Replacements
+	s.Evictions += o.Evictions
+	s.Loads += o.Loads
+	s.LoadErrors += o.LoadErrors
+}
+
+// Stats returns a copy of the usage statistics of the Map. All counters are
+// zero if the Map was not created with the [WithStats] option.
+func (m *Map[K, V]) Stats() Stats {
+	if m.stats == nil {
+		return Stats{}
+	}
+	return *m.stats
+}
+
+// ResetStats resets all usage counters to zero.
+func (m *Map[K, V]) ResetStats() {
+	if m.stats != nil {
+		*m.stats = Stats{}
+	}
+}
@@ -0,0 +1,61 @@
+package lru_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/db47h/cache/v2/hash"
+	"github.com/db47h/cache/v2/lru"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMap_Stats(t *testing.T) {
+	m := lru.NewMap[string, int](lru.WithStats())
+	for _, d := range td {
+		m.Set(d.key, d.value)
+	}
+	m.Set("earth", 42)
+	m.Get("mars")
+	m.Get("venus")
+	m.Get("pluto")
+	m.DeleteLRU()
+	m.Delete("jupiter")
+	require.Equal(t, lru.Stats{
+		Hits:         2,
+		Misses:       1,
+		Insertions:   uint64(len(td)),
+		Replacements: 1,
+		Evictions:    1,
+	}, m.Stats())
+	require.InDelta(t, 2.0/3.0, m.Stats().HitRatio(), 1e-9)
+
+	m.ResetStats()
+	require.Equal(t, lru.Stats{}, m.Stats())
+
+	// disabled
+	m = populate()
+	m.Get("mars")
+	require.Equal(t, lru.Stats{}, m.Stats())
+}
+
+func TestSyncMap_Stats(t *testing.T) {
+	m := lru.NewSyncMap[int, int](lru.WithStats(), lru.WithHasher(hash.Number[int]()))
+	load := func(k int) (int, error) {
+		if k < 0 {
+			return 0, errors.New("negative key")
+		}
+		return k, nil
+	}
+	for _, k := range []int{1, 2, 1, -1, 2, -1} {
+		m.GetWithDefault(k, load)
+	}
+	require.Equal(t, lru.Stats{
+		Hits:       2,
+		Misses:     4,
+		Insertions: 2,
+		Loads:      2,
+		LoadErrors: 2,
+	}, m.Stats())
+	m.ResetStats()
+	require.Equal(t, lru.Stats{}, m.Stats())
+}
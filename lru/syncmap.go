@@ -0,0 +1,701 @@
+package lru
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// SyncMap is a [Map] safe for concurrent use by multiple goroutines.
+//
+// Since Get updates the LRU list, most methods take an exclusive lock. Methods
+// that do not modify the Map, like Len, LRU or the iterators, only take a read
+// lock and can proceed concurrently.
+type SyncMap[K comparable, V any] struct {
+	mu     sync.RWMutex
+	m      Map[K, V]
+	calls  map[K]*call[V] // in-flight GetWithDefault calls
+	negTTL time.Duration
+	neg    Map[K, struct{}] // negative GetWithDefault results, see WithNegativeTTL
+
+	// deferred eviction callbacks, see WithDeferredOnEvict
+	deferOnEvict bool
+	onEvict      func(K, V)
+	batch        func([]K, []V) // see WithBatchOnEvict
+	evKeys       []K
+	evValues     []V
+
+	promo chan promotion[K] // pending promotions, see WithDeferredPromotion
+}
+
+// promotion is a pending promotion of a key found by Get.
+type promotion[K comparable] struct {
+	hash uint64
+	key  K
+}
+
+// call is an in-flight or completed GetWithDefault call.
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+	// panicked is set if fn panicked or called runtime.Goexit, in which case
+	// p holds the recovered value (nil for Goexit).
+	panicked bool
+	p        any
+	// canceled is set if fn failed after the context of the caller that ran
+	// it was done.
+	canceled bool
+}
+
+func NewSyncMap[K comparable, V any](opts ...Option) *SyncMap[K, V] {
+	var m SyncMap[K, V]
+	m.init(opts)
+	return &m
+}
+
+func (m *SyncMap[K, V]) Init(opts ...Option) {
+	m.lock()
+	m.init(opts)
+	m.mu.Unlock()
+}
+
+func (m *SyncMap[K, V]) init(opts []Option) {
+	o := getOpts[K](opts)
+	if o.batch != nil && o.onEvict != nil {
+		panic("lru: WithOnEvict and WithBatchOnEvict are mutually exclusive")
+	}
+	m.m.Init(opts...)
+	m.negTTL = o.negTTL
+	m.neg = Map[K, struct{}]{}
+	if m.negTTL > 0 {
+		m.neg.Init(WithHasher(m.m.hash), WithPolicy(PolicyFIFO))
+	}
+	m.deferOnEvict = o.deferred
+	m.promo = nil
+	if o.promoBatch > 0 {
+		m.promo = make(chan promotion[K], o.promoBatch)
+	}
+	m.evKeys, m.evValues = nil, nil
+	m.onEvict, m.batch = nil, nil
+	if o.batch != nil {
+		m.batch = o.batch.(func([]K, []V))
+		m.m.onEvict = m.queueEvicted
+	} else if m.deferOnEvict && m.m.onEvict != nil {
+		m.onEvict = m.m.onEvict
+		m.m.onEvict = m.queueEvicted
+	}
+}
+
+// queueEvicted records an evicted entry for a deferred eviction callback.
+func (m *SyncMap[K, V]) queueEvicted(key K, value V) {
+	m.evKeys = append(m.evKeys, key)
+	m.evValues = append(m.evValues, value)
+}
+
+// lock acquires the exclusive lock and applies the promotions queued by Get, if
+// any, so that operations under the lock see an up to date recency order.
+func (m *SyncMap[K, V]) lock() {
+	m.mu.Lock()
+	if m.promo != nil {
+		m.flushPromotions()
+	}
+}
+
+// unlock releases the exclusive lock, then runs the deferred eviction
+// callbacks, if any, for the entries evicted while the lock was held.
+func (m *SyncMap[K, V]) unlock() {
+	if len(m.evKeys) == 0 {
+		m.mu.Unlock()
+		return
+	}
+	fn, batch, keys, values := m.onEvict, m.batch, m.evKeys, m.evValues
+	m.evKeys, m.evValues = nil, nil
+	m.mu.Unlock()
+	if batch != nil {
+		batch(keys, values)
+		return
+	}
+	for i, k := range keys {
+		fn(k, values[i])
+	}
+}
+
+// SetOnEvict is a locked wrapper for [Map.SetOnEvict]. It also replaces the
+// callback set with [WithBatchOnEvict], if any.
+func (m *SyncMap[K, V]) SetOnEvict(onEvict func(K, V)) {
+	m.lock()
+	m.batch = nil
+	if m.deferOnEvict {
+		m.onEvict = onEvict
+		if onEvict != nil {
+			onEvict = m.queueEvicted
+		}
+	}
+	m.m.SetOnEvict(onEvict)
+	m.unlock()
+}
+
+// Set is a locked wrapper for [Map.Set].
+func (m *SyncMap[K, V]) Set(key K, value V) (prev V, replaced bool) {
+	m.lock()
+	prev, replaced = m.m.Set(key, value)
+	m.unlock()
+	return
+}
+
+// SetMulti is a locked wrapper for [Map.SetMulti]. The lock is held for the
+// whole batch.
+func (m *SyncMap[K, V]) SetMulti(keys []K, values []V) (n int) {
+	m.lock()
+	defer m.unlock()
+	return m.m.SetMulti(keys, values)
+}
+
+// SetOrdered is a locked wrapper for [Map.SetOrdered]. The lock is held while
+// iterating over pairs, so pairs must not call any method of m.
+func (m *SyncMap[K, V]) SetOrdered(pairs func(yield func(K, V) bool)) {
+	m.lock()
+	defer m.unlock()
+	m.m.SetOrdered(pairs)
+}
+
+// SetWithTTL is a locked wrapper for [Map.SetWithTTL].
+func (m *SyncMap[K, V]) SetWithTTL(key K, value V, ttl time.Duration) (prev V, replaced bool) {
+	m.lock()
+	prev, replaced = m.m.SetWithTTL(key, value, ttl)
+	m.unlock()
+	return
+}
+
+// Get is a locked wrapper for [Map.Get].
+//
+// With [WithDeferredPromotion], hits only take a read lock and the promotion
+// of the entry is queued. Queued promotions are applied in batches the next
+// time the exclusive lock is taken, by any method.
+func (m *SyncMap[K, V]) Get(key K) (value V, ok bool) {
+	if m.promo != nil {
+		if value, ok = m.getDeferred(key); ok {
+			return value, ok
+		}
+	}
+	m.lock()
+	value, ok = m.m.Get(key)
+	m.unlock()
+	return
+}
+
+// getDeferred looks up key under the read lock and queues its promotion. It
+// returns false on a miss or if the entry expired, in which case the caller
+// must fall back to a locked Get, which takes care of evicting expired entries
+// and of updating stats.
+func (m *SyncMap[K, V]) getDeferred(key K) (value V, ok bool) {
+	m.mu.RLock()
+	if m.m.capacity == 0 {
+		m.mu.RUnlock()
+		return value, false
+	}
+	return m.getDeferredRLocked(m.m.hash(key), key)
+}
+
+// getDeferredRLocked is like getDeferred for a key with the given hash. The
+// read lock must be held and is released before queuing the promotion.
+func (m *SyncMap[K, V]) getDeferredRLocked(hash uint64, key K) (value V, ok bool) {
+	if i := m.m.lookup(hash, key); i != 0 && !m.m.elms[i].expired() {
+		value, ok = m.m.elms[i].value, true
+	}
+	m.mu.RUnlock()
+	if !ok {
+		return value, false
+	}
+	select {
+	case m.promo <- promotion[K]{hash, key}:
+	default:
+		// queue full
+		m.lock()
+		m.promote(hash, key)
+		m.unlock()
+	}
+	return value, true
+}
+
+// FlushPromotions applies the promotions queued by Get when deferred promotion
+// is enabled with [WithDeferredPromotion]. Once it returns, the recency order
+// reflects all calls to Get that returned before FlushPromotions was called.
+func (m *SyncMap[K, V]) FlushPromotions() {
+	if m.promo == nil {
+		return
+	}
+	m.lock() // applies queued promotions
+	m.unlock()
+}
+
+// flushPromotions applies pending promotions. The lock must be held.
+func (m *SyncMap[K, V]) flushPromotions() {
+	for range cap(m.promo) {
+		select {
+		case p := <-m.promo:
+			m.promote(p.hash, p.key)
+		default:
+			return
+		}
+	}
+}
+
+// promote marks key as recently used if it is still in the Map and counts the
+// deferred hit. The lock must be held.
+func (m *SyncMap[K, V]) promote(hash uint64, key K) {
+	if i := m.m.lookup(hash, key); i != 0 {
+		m.m.touch(i)
+	}
+	if st := m.m.stats; st != nil {
+		st.Hits++
+	}
+}
+
+// ErrNotFound can be returned by the fn argument of GetWithDefault to signal
+// that no value exists for a key. See [WithNegativeTTL].
+var ErrNotFound = errors.New("lru: not found")
+
+// GetWithDefault returns the value for the given key. On a miss, it calls fn to
+// create a new value and inserts it, unless fn returns an error, in which case
+// the error is returned and nothing is inserted. As with [Map.GetWithDefault],
+// fn can return [ErrDoNotCache] to have its value returned but not inserted.
+//
+// If negative caching is enabled with [WithNegativeTTL] and fn returns
+// [ErrNotFound], further calls for the same key return ErrNotFound without
+// calling fn until the negative entry expires.
+//
+// The lock is not held while fn runs, so fn may take some time without
+// blocking access to other keys. Concurrent calls for the same missing key
+// share a single call to fn and all return its result.
+func (m *SyncMap[K, V]) GetWithDefault(key K, fn func(K) (V, error)) (V, error) {
+	return m.GetWithDefaultContext(context.Background(), key,
+		func(_ context.Context, key K) (V, error) { return fn(key) })
+}
+
+// GetWithDefaultContext is like [SyncMap.GetWithDefault] but passes ctx to fn.
+// If ctx is done before a value is available, its error is returned. In
+// particular, fn is not called if ctx is already done.
+//
+// When concurrent calls share a single call to fn, fn receives the context of
+// the caller that runs it. If that context is done and fn fails, callers whose
+// own context is still live retry, and one of them calls fn again. If fn
+// panics, the panic is propagated to all callers sharing that call and the
+// next call for key calls fn again.
+func (m *SyncMap[K, V]) GetWithDefaultContext(ctx context.Context, key K, fn func(context.Context, K) (V, error)) (V, error) {
+	var zero V
+	for {
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		m.lock()
+		if v, ok := m.m.Get(key); ok {
+			m.unlock()
+			return v, nil
+		}
+		if m.negTTL > 0 {
+			if _, ok := m.neg.Get(key); ok {
+				m.unlock()
+				return zero, ErrNotFound
+			}
+		}
+		if c, ok := m.calls[key]; ok {
+			m.unlock()
+			select {
+			case <-c.done:
+			case <-ctx.Done():
+				return zero, ctx.Err()
+			}
+			if c.panicked {
+				if c.p == nil {
+					return zero, errGoexit
+				}
+				panic(c.p)
+			}
+			if c.canceled {
+				continue
+			}
+			return c.value, c.err
+		}
+		c := &call[V]{done: make(chan struct{})}
+		if m.calls == nil {
+			m.calls = make(map[K]*call[V])
+		}
+		m.calls[key] = c
+		m.unlock()
+		return m.doCall(ctx, c, key, fn)
+	}
+}
+
+// errGoexit is returned to the callers sharing a call to fn that called
+// runtime.Goexit.
+var errGoexit = errors.New("lru: GetWithDefault: fn called runtime.Goexit")
+
+// doCall runs fn on behalf of all the callers sharing c and publishes the
+// result.
+func (m *SyncMap[K, V]) doCall(ctx context.Context, c *call[V], key K, fn func(context.Context, K) (V, error)) (V, error) {
+	normal := false
+	defer func() {
+		if normal {
+			return
+		}
+		// fn panicked or called runtime.Goexit: forget about the call so that
+		// the next one retries, and propagate the panic to the waiters.
+		c.panicked = true
+		c.p = recover()
+		m.lock()
+		delete(m.calls, key)
+		m.unlock()
+		close(c.done)
+		if c.p != nil {
+			panic(c.p)
+		}
+	}()
+	c.value, c.err = fn(ctx, key)
+	normal = true
+
+	store := c.err == nil
+	if errors.Is(c.err, ErrDoNotCache) {
+		c.err = nil
+	}
+	c.canceled = c.err != nil && ctx.Err() != nil
+
+	m.lock()
+	delete(m.calls, key)
+	if store {
+		m.m.Set(key, c.value)
+	} else if m.negTTL > 0 && errors.Is(c.err, ErrNotFound) {
+		m.setNegative(key)
+	}
+	if st := m.m.stats; st != nil {
+		if c.err == nil {
+			st.Loads++
+		} else {
+			st.LoadErrors++
+		}
+	}
+	m.unlock()
+	close(c.done)
+	return c.value, c.err
+}
+
+// setNegative records a negative result for key. All negative entries have the
+// same time to live and are kept in insertion order, so expired ones are purged
+// from the LRU end.
+func (m *SyncMap[K, V]) setNegative(key K) {
+	for i := m.neg.lru(); i != 0 && m.neg.elms[i].expired(); i = m.neg.lru() {
+		m.neg.evict(i)
+	}
+	m.neg.SetWithTTL(key, struct{}{}, m.negTTL)
+}
+
+// GetMulti is a locked wrapper for [Map.GetMulti]. The lock is held for the
+// whole batch.
+func (m *SyncMap[K, V]) GetMulti(keys []K) (values []V, found []bool) {
+	m.lock()
+	values, found = m.m.GetMulti(keys)
+	m.unlock()
+	return
+}
+
+// GetFresh is a locked wrapper for [Map.GetFresh].
+func (m *SyncMap[K, V]) GetFresh(key K) (value V, state LookupState) {
+	m.lock()
+	value, state = m.m.GetFresh(key)
+	m.unlock()
+	return
+}
+
+// Touch is a locked wrapper for [Map.Touch].
+func (m *SyncMap[K, V]) Touch(key K) (ok bool) {
+	m.lock()
+	ok = m.m.Touch(key)
+	m.unlock()
+	return
+}
+
+// UpdateSize is a locked wrapper for [Map.UpdateSize].
+func (m *SyncMap[K, V]) UpdateSize(key K, oldSize int64) (ok bool) {
+	m.lock()
+	ok = m.m.UpdateSize(key, oldSize)
+	m.unlock()
+	return
+}
+
+// Peek is a locked wrapper for [Map.Peek].
+func (m *SyncMap[K, V]) Peek(key K) (value V, ok bool) {
+	m.lock()
+	value, ok = m.m.Peek(key)
+	m.unlock()
+	return
+}
+
+// Delete is a locked wrapper for [Map.Delete].
+func (m *SyncMap[K, V]) Delete(key K) (value V, ok bool) {
+	m.lock()
+	value, ok = m.m.Delete(key)
+	m.unlock()
+	return
+}
+
+// Take is a locked wrapper for [Map.Take]. When several goroutines take the
+// same key concurrently, only one of them gets the value.
+func (m *SyncMap[K, V]) Take(key K) (value V, ok bool) {
+	m.lock()
+	value, ok = m.m.Take(key)
+	m.unlock()
+	return
+}
+
+// DeleteLRU is a locked wrapper for [Map.DeleteLRU].
+func (m *SyncMap[K, V]) DeleteLRU() (key K, value V) {
+	m.lock()
+	key, value = m.m.DeleteLRU()
+	m.unlock()
+	return
+}
+
+// Pin is a locked wrapper for [Map.Pin].
+func (m *SyncMap[K, V]) Pin(key K) (ok bool) {
+	m.lock()
+	ok = m.m.Pin(key)
+	m.unlock()
+	return
+}
+
+// Unpin is a locked wrapper for [Map.Unpin].
+func (m *SyncMap[K, V]) Unpin(key K) (ok bool) {
+	m.lock()
+	ok = m.m.Unpin(key)
+	m.unlock()
+	return
+}
+
+// Drain is a locked wrapper for [Map.Drain].
+func (m *SyncMap[K, V]) Drain() []V {
+	m.lock()
+	defer m.unlock()
+	return m.m.Drain()
+}
+
+// EvictToSize is a locked wrapper for [Map.EvictToSize].
+func (m *SyncMap[K, V]) EvictToSize(max int64) (n int) {
+	m.lock()
+	n = m.m.EvictToSize(max)
+	m.unlock()
+	return
+}
+
+// EvictToLimits is a locked wrapper for [Map.EvictToLimits].
+func (m *SyncMap[K, V]) EvictToLimits(maxLen int, maxBytes int64) (n int) {
+	m.lock()
+	n = m.m.EvictToLimits(maxLen, maxBytes)
+	m.unlock()
+	return
+}
+
+// PurgeExpired is a locked wrapper for [Map.PurgeExpired].
+func (m *SyncMap[K, V]) PurgeExpired() int {
+	m.lock()
+	defer m.unlock()
+	return m.m.PurgeExpired()
+}
+
+// StartJanitor starts a goroutine that calls [SyncMap.PurgeExpired] at the given
+// interval. See [Map.StartJanitor]. It panics if interval <= 0.
+func (m *SyncMap[K, V]) StartJanitor(interval time.Duration) (stop func()) {
+	return m.m.StartJanitor(interval, janitorLock[K, V]{m})
+}
+
+// janitorLock is the lock used by the janitor of a SyncMap, so that deferred
+// eviction callbacks run once it releases the lock.
+type janitorLock[K comparable, V any] struct{ m *SyncMap[K, V] }
+
+func (l janitorLock[K, V]) Lock()   { l.m.lock() }
+func (l janitorLock[K, V]) Unlock() { l.m.unlock() }
+
+// Rehash is a locked wrapper for [Map.Rehash].
+func (m *SyncMap[K, V]) Rehash(hasher func(K) uint64) {
+	m.lock()
+	m.m.Rehash(hasher)
+	m.unlock()
+}
+
+// SetCapacity is a locked wrapper for [Map.SetCapacity].
+func (m *SyncMap[K, V]) SetCapacity(capacity int) {
+	m.lock()
+	m.m.SetCapacity(capacity)
+	m.unlock()
+}
+
+// Keys returns an iterator for all keys in the Map, lru first.
+//
+// A read lock is held for the whole duration of the iteration, so the loop
+// body must not call any method of m.
+func (m *SyncMap[K, V]) Keys() func(yield func(K) bool) {
+	return func(yield func(K) bool) {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		m.m.Keys()(yield)
+	}
+}
+
+// Values returns an iterator for all values in the Map, lru first.
+//
+// A read lock is held for the whole duration of the iteration, so the loop
+// body must not call any method of m.
+func (m *SyncMap[K, V]) Values() func(yield func(V) bool) {
+	return func(yield func(V) bool) {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		m.m.Values()(yield)
+	}
+}
+
+// All returns an iterator for all key value pairs in the Map, lru first.
+//
+// A read lock is held for the whole duration of the iteration, so the loop
+// body must not call any method of m.
+func (m *SyncMap[K, V]) All() func(yield func(K, V) bool) {
+	return func(yield func(K, V) bool) {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		m.m.All()(yield)
+	}
+}
+
+// Entry is a key value pair. See [SyncMap.Collect].
+type Entry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// Collect returns a copy of all entries in the Map, lru first. Unlike with the
+// iterators, the read lock is only held while copying, so the result can be
+// processed at length without blocking writers. It is a point-in-time view:
+// changes made to the Map after Collect returns are not reflected in it.
+func (m *SyncMap[K, V]) Collect() []Entry[K, V] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	es := make([]Entry[K, V], 0, m.m.Len())
+	for k, v := range m.m.All() {
+		es = append(es, Entry[K, V]{k, v})
+	}
+	return es
+}
+
+// SortedKeys is a wrapper for [Map.SortedKeys]. The read lock is only held
+// while copying the keys, not while sorting them.
+func (m *SyncMap[K, V]) SortedKeys(less func(a, b K) bool) []K {
+	m.mu.RLock()
+	keys := m.m.liveKeys()
+	m.mu.RUnlock()
+	sortKeys(keys, less)
+	return keys
+}
+
+// KeysMRU returns an iterator for all keys in the Map, mru first.
+//
+// A read lock is held for the whole duration of the iteration, so the loop
+// body must not call any method of m.
+func (m *SyncMap[K, V]) KeysMRU() func(yield func(K) bool) {
+	return func(yield func(K) bool) {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		m.m.KeysMRU()(yield)
+	}
+}
+
+// ValuesMRU returns an iterator for all values in the Map, mru first.
+//
+// A read lock is held for the whole duration of the iteration, so the loop
+// body must not call any method of m.
+func (m *SyncMap[K, V]) ValuesMRU() func(yield func(V) bool) {
+	return func(yield func(V) bool) {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		m.m.ValuesMRU()(yield)
+	}
+}
+
+// AllMRU returns an iterator for all key value pairs in the Map, mru first.
+//
+// A read lock is held for the whole duration of the iteration, so the loop
+// body must not call any method of m.
+func (m *SyncMap[K, V]) AllMRU() func(yield func(K, V) bool) {
+	return func(yield func(K, V) bool) {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		m.m.AllMRU()(yield)
+	}
+}
+
+// AppendKeys is a locked wrapper for [Map.AppendKeys].
+func (m *SyncMap[K, V]) AppendKeys(dst []K) []K {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.m.AppendKeys(dst)
+}
+
+// AppendValues is a locked wrapper for [Map.AppendValues].
+func (m *SyncMap[K, V]) AppendValues(dst []V) []V {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.m.AppendValues(dst)
+}
+
+// LRU is a locked wrapper for [Map.LRU].
+func (m *SyncMap[K, V]) LRU() (key K, value V) {
+	m.mu.RLock()
+	key, value = m.m.LRU()
+	m.mu.RUnlock()
+	return
+}
+
+// MRU is a locked wrapper for [Map.MRU].
+func (m *SyncMap[K, V]) MRU() (key K, value V) {
+	m.mu.RLock()
+	key, value = m.m.MRU()
+	m.mu.RUnlock()
+	return
+}
+
+func (m *SyncMap[K, V]) Load() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.m.Load()
+}
+
+func (m *SyncMap[K, V]) Capacity() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.m.Capacity()
+}
+
+func (m *SyncMap[K, V]) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.m.Len()
+}
+
+func (m *SyncMap[K, V]) Size() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.m.Size()
+}
+
+// Stats is a locked wrapper for [Map.Stats].
+func (m *SyncMap[K, V]) Stats() Stats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.m.Stats()
+}
+
+// ResetStats is a locked wrapper for [Map.ResetStats].
+func (m *SyncMap[K, V]) ResetStats() {
+	m.lock()
+	m.m.ResetStats()
+	m.unlock()
+}
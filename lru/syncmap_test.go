@@ -0,0 +1,499 @@
+package lru_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/db47h/cache/v2/hash"
+	"github.com/db47h/cache/v2/lru"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncMap(t *testing.T) {
+	const (
+		workers = 8
+		iters   = 10000
+		maxLen  = 100
+	)
+	m := lru.NewSyncMap[int, int](lru.WithHasher(hash.Number[int]()))
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			xo := &Xorshift64S{uint64(w + 1)}
+			for range iters {
+				k := xo.IntN(maxLen * 2)
+				if v, ok := m.Get(k); ok {
+					if v != k {
+						t.Errorf("Get(%d): got %d", k, v)
+					}
+					continue
+				}
+				m.Set(k, k)
+				for m.Len() > maxLen {
+					m.DeleteLRU()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.LessOrEqual(t, m.Len(), maxLen+workers)
+	n := 0
+	for k, v := range m.All() {
+		require.Equal(t, k, v)
+		n++
+	}
+	require.Equal(t, m.Len(), n)
+}
+
+func TestSyncMap_allocs(t *testing.T) {
+	m := lru.NewSyncMap[int, int](lru.WithHasher(hash.Number[int]()))
+	for i := range 10 {
+		m.Set(i, i)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		for i := range 10 {
+			m.Get(i)
+		}
+	})
+	require.Zero(t, allocs)
+}
+
+func TestSyncMap_GetMulti(t *testing.T) {
+	m := lru.NewSyncMap[int, int](lru.WithHasher(hash.Number[int]()))
+	for i := range 10 {
+		m.Set(i, i*10)
+	}
+	values, found := m.GetMulti([]int{3, 42, 1})
+	require.Equal(t, []int{30, 0, 10}, values)
+	require.Equal(t, []bool{true, false, true}, found)
+	k, _ := m.MRU()
+	require.Equal(t, 1, k)
+}
+
+func Benchmark_SyncMap_parallel(b *testing.B) {
+	const (
+		n       = 1 << 16
+		workers = 16
+	)
+	m := lru.NewSyncMap[int, int](lru.WithCapacity(n*2), lru.WithHasher(hash.Number[int]()))
+	d := lru.NewSyncMap[int, int](lru.WithCapacity(n*2), lru.WithHasher(hash.Number[int]()),
+		lru.WithDeferredPromotion(256))
+	for i := range n {
+		m.Set(i, i)
+		d.Set(i, i)
+	}
+	for _, bb := range []struct {
+		name string
+		f    func(int)
+	}{
+		{"Get", func(i int) { m.Get(i & (n - 1)) }},
+		{"GetDeferred", func(i int) { d.Get(i & (n - 1)) }},
+		{"LRU", func(int) { m.LRU() }},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			var wg sync.WaitGroup
+			for w := range workers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := w; i < b.N; i += workers {
+						bb.f(i * 7919)
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestSyncMap_WithDeferredPromotion(t *testing.T) {
+	m := lru.NewSyncMap[int, int](lru.WithHasher(hash.Number[int]()),
+		lru.WithDeferredPromotion(4), lru.WithStats())
+	for i := range 8 {
+		m.Set(i, i)
+	}
+	m.Get(0)
+	m.Get(1)
+	k, _ := m.LRU()
+	require.Equal(t, 0, k)
+	require.Zero(t, m.Stats().Hits)
+	m.FlushPromotions()
+	k, _ = m.LRU()
+	require.Equal(t, 2, k)
+	k, _ = m.MRU()
+	require.Equal(t, 1, k)
+	require.Equal(t, 2, int(m.Stats().Hits))
+
+	// queued promotions are applied when taking the exclusive lock
+	m.Get(2)
+	_, ok := m.Get(42)
+	require.False(t, ok)
+	k, _ = m.MRU()
+	require.Equal(t, 2, k)
+	st := m.Stats()
+	require.Equal(t, 3, int(st.Hits))
+	require.Equal(t, 1, int(st.Misses))
+	m.Get(3)
+	k, _ = m.DeleteLRU()
+	require.Equal(t, 4, k)
+	m.Set(4, 4)
+
+	// a full queue is flushed by Get
+	for _, i := range []int{5, 6, 7, 0, 1} {
+		m.Get(i)
+	}
+	require.Equal(t, []int{2, 3, 4, 5, 6, 7, 0, 1}, slices.Collect(m.Keys()))
+
+	// concurrent use
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				m.Get((w + i) % 8)
+				if i%100 == 0 {
+					m.Set(i%8, i)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	m.FlushPromotions()
+	require.Equal(t, 8, m.Len())
+}
+
+func Benchmark_SyncMap_deferredPromotion(b *testing.B) {
+	const n = 1 << 16
+	for _, mode := range []struct {
+		name string
+		opts []lru.Option
+	}{
+		{"locked", nil},
+		{"deferred", []lru.Option{lru.WithDeferredPromotion(256)}},
+	} {
+		m := lru.NewSyncMap[int, int](append(mode.opts, lru.WithCapacity(n*2), lru.WithHasher(hash.Number[int]()))...)
+		for i := range n {
+			m.Set(i, i)
+		}
+		for _, workers := range []int{1, 4, 16, 64} {
+			b.Run(mode.name+"/"+strconv.Itoa(workers), func(b *testing.B) {
+				var wg sync.WaitGroup
+				for w := range workers {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for i := w; i < b.N; i += workers {
+							m.Get((i * 7919) & (n - 1))
+						}
+					}()
+				}
+				wg.Wait()
+			})
+		}
+	}
+}
+
+func TestSyncMap_GetWithDefault(t *testing.T) {
+	const workers = 32
+	m := lru.NewSyncMap[int, int](lru.WithHasher(hash.Number[int]()))
+	var (
+		calls   atomic.Int32
+		release = make(chan struct{})
+		wg      sync.WaitGroup
+	)
+	fn := func(k int) (int, error) {
+		calls.Add(1)
+		<-release
+		return k * 10, nil
+	}
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := m.GetWithDefault(1, fn)
+			if err != nil || v != 10 {
+				t.Errorf("got %d, %v", v, err)
+			}
+		}()
+	}
+	// other keys are not blocked while fn runs
+	for calls.Load() == 0 {
+		runtime.Gosched()
+	}
+	m.Set(2, 2)
+	v, ok := m.Get(2)
+	require.True(t, ok)
+	require.Equal(t, 2, v)
+
+	close(release)
+	wg.Wait()
+	require.Equal(t, int32(1), calls.Load())
+	v, ok = m.Peek(1)
+	require.True(t, ok)
+	require.Equal(t, 10, v)
+
+	// errors are returned and nothing is inserted
+	errFail := errors.New("fail")
+	_, err := m.GetWithDefault(3, func(int) (int, error) { return 0, errFail })
+	require.ErrorIs(t, err, errFail)
+	_, ok = m.Peek(3)
+	require.False(t, ok)
+	require.Equal(t, 2, m.Len())
+
+	// ErrDoNotCache: value returned, nothing inserted, fn called again
+	calls.Store(0)
+	for range 2 {
+		v, err := m.GetWithDefault(4, func(k int) (int, error) {
+			calls.Add(1)
+			return k, lru.ErrDoNotCache
+		})
+		require.NoError(t, err)
+		require.Equal(t, 4, v)
+	}
+	require.Equal(t, int32(2), calls.Load())
+	require.Equal(t, 2, m.Len())
+}
+
+func TestSyncMap_GetWithDefaultContext(t *testing.T) {
+	m := lru.NewSyncMap[int, int](lru.WithHasher(hash.Number[int]()))
+	fn := func(ctx context.Context, k int) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+
+	// cancelled on entry: fn is not called
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := m.GetWithDefaultContext(ctx, 1, func(context.Context, int) (int, error) {
+		t.Fatal("fn called")
+		return 0, nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	// deadline honored by fn
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = m.GetWithDefaultContext(ctx, 1, fn)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Zero(t, m.Len())
+
+	// a waiter gives up when its own context is done
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		m.GetWithDefaultContext(context.Background(), 2, func(context.Context, int) (int, error) {
+			close(started)
+			<-release
+			return 2, nil
+		})
+	}()
+	<-started
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = m.GetWithDefaultContext(ctx, 2, fn)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	close(release)
+
+	// waiters retry when the context of the caller running fn is done
+	ctx, cancel = context.WithCancel(context.Background())
+	started = make(chan struct{})
+	go func() {
+		m.GetWithDefaultContext(ctx, 4, func(ctx context.Context, _ int) (int, error) {
+			close(started)
+			<-ctx.Done()
+			return 0, ctx.Err()
+		})
+	}()
+	<-started
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		v, err := m.GetWithDefaultContext(context.Background(), 4, func(_ context.Context, k int) (int, error) { return k, nil })
+		require.NoError(t, err)
+		require.Equal(t, 4, v)
+	}()
+	cancel()
+	<-done
+
+	v, err := m.GetWithDefault(3, func(k int) (int, error) { return k, nil })
+	require.NoError(t, err)
+	require.Equal(t, 3, v)
+}
+
+func TestSyncMap_GetWithDefault_panic(t *testing.T) {
+	m := lru.NewSyncMap[int, int](lru.WithHasher(hash.Number[int]()))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	waiter := make(chan any)
+	go func() {
+		<-started
+		time.AfterFunc(10*time.Millisecond, func() { close(release) })
+		defer func() { waiter <- recover() }()
+		m.GetWithDefault(1, func(int) (int, error) { return 0, nil })
+	}()
+	require.PanicsWithValue(t, "boom", func() {
+		m.GetWithDefault(1, func(int) (int, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	})
+	require.Equal(t, "boom", <-waiter)
+
+	// the failed call is forgotten
+	v, err := m.GetWithDefault(1, func(k int) (int, error) { return k, nil })
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+}
+
+func TestSyncMap_WithDeferredOnEvict(t *testing.T) {
+	var (
+		evicted []int
+		started = make(chan struct{})
+		release = make(chan struct{})
+		m       *lru.SyncMap[int, int]
+	)
+	m = lru.NewSyncMap[int, int](lru.WithHasher(hash.Number[int]()),
+		lru.WithSizer(func(int) int64 { return 1 }),
+		lru.WithDeferredOnEvict(),
+		lru.WithOnEvict(func(k, v int) {
+			if k == 0 {
+				close(started)
+				<-release
+			}
+			// the lock is not held: accessing the SyncMap does not deadlock
+			m.Peek(k)
+			evicted = append(evicted, v)
+		}))
+	for i := range 10 {
+		m.Set(i, i)
+	}
+
+	done := make(chan int)
+	go func() { done <- m.EvictToSize(5) }()
+	<-started
+	// other operations are not blocked while the callback runs
+	m.Set(10, 10)
+	v, ok := m.Get(7)
+	require.True(t, ok)
+	require.Equal(t, 7, v)
+	require.Equal(t, 6, m.Len())
+	close(release)
+	require.Equal(t, 5, <-done)
+	require.Equal(t, []int{0, 1, 2, 3, 4}, evicted)
+}
+
+func TestSyncMap_WithBatchOnEvict(t *testing.T) {
+	var batches [][]int
+	m := lru.NewSyncMap[int, int](lru.WithHasher(hash.Number[int]()),
+		lru.WithSizer(func(int) int64 { return 1 }),
+		lru.WithBatchOnEvict(func(keys []int, values []int) {
+			require.Equal(t, keys, values)
+			batches = append(batches, values)
+		}))
+	for i := range 10 {
+		m.Set(i, i)
+	}
+	require.Equal(t, 5, m.EvictToSize(5))
+	require.Equal(t, [][]int{{0, 1, 2, 3, 4}}, batches)
+	// nothing evicted, no call
+	m.Delete(5)
+	require.Len(t, batches, 1)
+
+	require.Panics(t, func() {
+		lru.NewSyncMap[int, int](lru.WithOnEvict(func(int, int) {}),
+			lru.WithBatchOnEvict(func([]int, []int) {}))
+	})
+	// plain Maps ignore the batch callback
+	require.NotPanics(t, func() {
+		lru.NewMap[int, int](lru.WithOnEvict(func(int, int) {}),
+			lru.WithBatchOnEvict(func([]int, []int) {}))
+	})
+}
+
+func TestSyncMap_Take(t *testing.T) {
+	m := lru.NewSyncMap[int, int](lru.WithHasher(hash.Number[int]()))
+	for k := range 100 {
+		m.Set(k, k)
+	}
+	for k := range 100 {
+		var (
+			wg sync.WaitGroup
+			n  atomic.Int32
+		)
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if v, ok := m.Take(k); ok {
+					require.Equal(t, k, v)
+					n.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		require.Equal(t, int32(1), n.Load())
+	}
+	require.Zero(t, m.Len())
+}
+
+func TestSyncMap_Peek(t *testing.T) {
+	m := lru.NewSyncMap[int, int](lru.WithHasher(hash.Number[int]()))
+	for i := range 10 {
+		m.Set(i, i)
+	}
+	v, ok := m.Peek(0)
+	require.True(t, ok)
+	require.Zero(t, v)
+	k, _ := m.LRU()
+	require.Equal(t, 0, k)
+	_, ok = m.Peek(10)
+	require.False(t, ok)
+}
+
+func TestSyncMap_AllMRU(t *testing.T) {
+	m := lru.NewSyncMap[int, int](lru.WithHasher(hash.Number[int]()))
+	for i := range 5 {
+		m.Set(i, -i)
+	}
+	m.Get(2)
+	require.Equal(t, []int{2, 4, 3, 1, 0}, slices.Collect(m.KeysMRU()))
+	require.Equal(t, []int{-2, -4, -3, -1, 0}, slices.Collect(m.ValuesMRU()))
+	var keys []int
+	for k, v := range m.AllMRU() {
+		require.Equal(t, -k, v)
+		keys = append(keys, k)
+		if k == 3 {
+			break
+		}
+	}
+	require.Equal(t, []int{2, 4, 3}, keys)
+}
+
+func TestSyncMap_Collect(t *testing.T) {
+	m := lru.NewSyncMap[int, int](lru.WithHasher(hash.Number[int]()))
+	require.Empty(t, m.Collect())
+	for i := range 5 {
+		m.Set(i, i*10)
+	}
+	es := m.Collect()
+	m.Set(5, 50)
+	m.Set(0, 1)
+	m.Delete(3)
+	// the lock is released: iterating the copy can call into m
+	for _, e := range es {
+		m.Peek(e.Key)
+	}
+	require.Equal(t, []lru.Entry[int, int]{{0, 0}, {1, 10}, {2, 20}, {3, 30}, {4, 40}}, es)
+}
@@ -0,0 +1,108 @@
+package lru
+
+// Tiered is a two level cache: an in-process L1 [Cache] backed by a larger,
+// slower L2 store, like a remote cache or a database, accessed through the load
+// and store callbacks given to [NewTiered].
+//
+// On a miss in L1, Get loads the value from L2 and promotes it into L1. By
+// default, Set writes through to L2. With [WithWriteBack], Set only writes to
+// L1 and modified entries are written to L2 when evicted from L1 or by
+// [Tiered.Flush]. With [WithOnDemote], all entries evicted from L1 are also
+// passed to a callback, e.g. to push them down to L2.
+//
+// Like a Cache, a Tiered is not safe for concurrent use.
+type Tiered[K comparable, V any] struct {
+	l1        *Cache[K, V]
+	load      func(K) (V, bool)
+	store     func(K, V)
+	onDemote  func(K, V)
+	writeBack bool
+	dirty     map[K]struct{}
+}
+
+// NewTiered returns a new Tiered whose L1 is a [Cache] holding at most maxLen
+// entries, created with the given options. load returns the value for a key
+// from L2 and whether it was found, store writes a value to L2.
+func NewTiered[K comparable, V any](maxLen int, load func(K) (V, bool), store func(K, V), opts ...Option) *Tiered[K, V] {
+	o := getOpts[K](opts)
+	t := &Tiered[K, V]{
+		l1:        New[K, V](maxLen, opts...),
+		load:      load,
+		store:     store,
+		writeBack: o.writeBack,
+	}
+	if o.onDemote != nil {
+		t.onDemote = o.onDemote.(func(K, V))
+	}
+	if t.writeBack {
+		t.dirty = make(map[K]struct{})
+	}
+	onEvict := t.l1.m.onEvict
+	t.l1.m.onEvict = func(key K, value V) {
+		t.demote(key, value)
+		if onEvict != nil {
+			onEvict(key, value)
+		}
+	}
+	return t
+}
+
+// demote is called for every entry evicted from L1.
+func (t *Tiered[K, V]) demote(key K, value V) {
+	if _, ok := t.dirty[key]; ok {
+		delete(t.dirty, key)
+		t.store(key, value)
+	}
+	if t.onDemote != nil {
+		t.onDemote(key, value)
+	}
+}
+
+// Get returns the value for the given key from L1 or, on a miss, from L2, in
+// which case the value is promoted into L1.
+func (t *Tiered[K, V]) Get(key K) (V, bool) {
+	if v, ok := t.l1.Get(key); ok {
+		return v, true
+	}
+	v, ok := t.load(key)
+	if ok {
+		t.l1.Set(key, v)
+	}
+	return v, ok
+}
+
+// Set sets the value for the given key in L1 and, unless write back is
+// enabled, in L2. Values too large to ever fit in L1 are always written to L2.
+func (t *Tiered[K, V]) Set(key K, value V) {
+	if t.writeBack && !t.l1.tooLarge(value) {
+		t.dirty[key] = struct{}{}
+		t.l1.Set(key, value)
+		return
+	}
+	t.l1.Set(key, value)
+	t.store(key, value)
+}
+
+// Delete deletes the given key from L1. Pending writes for that key are
+// discarded, and L2 is left unchanged.
+func (t *Tiered[K, V]) Delete(key K) (V, bool) {
+	delete(t.dirty, key)
+	return t.l1.Delete(key)
+}
+
+// Flush writes all modified entries still in L1 to L2 and returns the number
+// of entries written. It is a no-op unless write back is enabled.
+func (t *Tiered[K, V]) Flush() int {
+	n := 0
+	for k := range t.dirty {
+		if v, ok := t.l1.Peek(k); ok {
+			t.store(k, v)
+			n++
+		}
+		delete(t.dirty, k)
+	}
+	return n
+}
+
+// L1 returns the L1 Cache.
+func (t *Tiered[K, V]) L1() *Cache[K, V] { return t.l1 }
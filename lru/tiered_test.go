@@ -0,0 +1,85 @@
+package lru_test
+
+import (
+	"testing"
+
+	"github.com/db47h/cache/v2/hash"
+	"github.com/db47h/cache/v2/lru"
+	"github.com/stretchr/testify/require"
+)
+
+type l2Store struct {
+	m      map[int]int
+	loads  int
+	stores int
+}
+
+func (s *l2Store) load(k int) (int, bool) {
+	s.loads++
+	v, ok := s.m[k]
+	return v, ok
+}
+
+func (s *l2Store) store(k, v int) {
+	s.stores++
+	s.m[k] = v
+}
+
+func TestTiered(t *testing.T) {
+	l2 := &l2Store{m: map[int]int{1: 10, 2: 20, 3: 30}}
+	var demoted []int
+	c := lru.NewTiered(2, l2.load, l2.store, lru.WithHasher(hash.Number[int]()),
+		lru.WithOnDemote(func(k, _ int) { demoted = append(demoted, k) }))
+
+	// promote on L2 hit
+	v, ok := c.Get(1)
+	require.True(t, ok)
+	require.Equal(t, 10, v)
+	require.Equal(t, 1, l2.loads)
+	v, ok = c.Get(1)
+	require.True(t, ok)
+	require.Equal(t, 10, v)
+	require.Equal(t, 1, l2.loads)
+	_, ok = c.Get(4)
+	require.False(t, ok)
+	require.Equal(t, 1, c.L1().Len())
+
+	// write through
+	c.Set(4, 40)
+	require.Equal(t, 40, l2.m[4])
+	require.Equal(t, 1, l2.stores)
+
+	// demote on L1 evict
+	c.Get(2)
+	require.Equal(t, []int{1}, demoted)
+	require.Equal(t, 2, c.L1().Len())
+}
+
+func TestTiered_WithWriteBack(t *testing.T) {
+	l2 := &l2Store{m: map[int]int{}}
+	c := lru.NewTiered(2, l2.load, l2.store, lru.WithHasher(hash.Number[int]()),
+		lru.WithWriteBack())
+	c.Set(1, 10)
+	c.Set(2, 20)
+	c.Set(2, 21)
+	require.Zero(t, l2.stores)
+
+	// dirty entries are written on eviction
+	c.Set(3, 30)
+	require.Equal(t, map[int]int{1: 10}, l2.m)
+	v, ok := c.Get(1)
+	require.True(t, ok)
+	require.Equal(t, 10, v)
+	require.Equal(t, map[int]int{1: 10, 2: 21}, l2.m)
+
+	// clean entries are not written again
+	c.Set(4, 40)
+	require.Equal(t, 3, l2.stores)
+	c.Get(4)
+	c.Set(5, 50)
+	require.Equal(t, 3, l2.stores)
+	c.Delete(5)
+	require.Equal(t, 1, c.Flush())
+	require.Equal(t, map[int]int{1: 10, 2: 21, 3: 30, 4: 40}, l2.m)
+	require.Zero(t, c.Flush())
+}
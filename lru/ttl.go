@@ -0,0 +1,169 @@
+package lru
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// nanotime returns the current time in nanoseconds. Replaced in tests.
+var nanotime = func() int64 { return time.Now().UnixNano() }
+
+// expiry returns the expiration timestamp for the given ttl, or 0 if ttl <= 0.
+func expiry(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return nanotime() + int64(ttl)
+}
+
+func (e *element[K, V]) expired() bool {
+	return e.expires != 0 && e.expires <= nanotime()
+}
+
+// trackExpiry records the expiration time of a new or updated entry for
+// WithExpiredFirst.
+func (m *Map[K, V]) trackExpiry(expires int64) {
+	if m.expFirst && expires != 0 && (m.nextExp == 0 || expires < m.nextExp) {
+		m.nextExp = expires
+	}
+}
+
+// expiredVictim returns the index of the least recently used unpinned expired
+// entry, or 0 if there is none, along with the earliest expiration time of the
+// remaining entries, 0 if none of them expire. It only scans the Map if some
+// entry may have expired, otherwise it returns 0, m.nextExp.
+func (m *Map[K, V]) expiredVictim() (int, int64) {
+	now := nanotime()
+	if m.nextExp == 0 || now < m.nextExp {
+		return 0, m.nextExp
+	}
+	var next int64
+	for i := m.lru(); i != 0; i = m.elms[i].prev {
+		it := &m.elms[i]
+		switch {
+		case it.expires == 0 || it.pinned:
+		case it.expires <= now:
+			return i, now
+		case next == 0 || it.expires < next:
+			next = it.expires
+		}
+	}
+	return 0, next
+}
+
+// SetWithTTL is like [Map.Set] but the entry will expire after the given
+// duration, regardless of its recency. A ttl <= 0 means no expiration.
+//
+// Expired entries are removed lazily: they are reported as missing by Get,
+// Peek and Delete, but are still present in the Map, counted by Len and
+// returned by the iterators and LRU/MRU until they are looked up or purged with
+// [Map.PurgeExpired].
+func (m *Map[K, V]) SetWithTTL(key K, value V, ttl time.Duration) (prev V, replaced bool) {
+	hash, i := m.find(key)
+	return m.set(hash, i, key, value, expiry(ttl))
+}
+
+// LookupState is the outcome of [Map.GetFresh].
+type LookupState uint8
+
+const (
+	// Miss means that the key was not found.
+	Miss LookupState = iota
+	// Hit means that the key was found and not expired.
+	Hit
+	// Expired means that the key was found but had expired. The entry has
+	// been evicted.
+	Expired
+)
+
+var lookupStates = [...]string{"miss", "hit", "expired"}
+
+func (s LookupState) String() string {
+	if int(s) < len(lookupStates) {
+		return lookupStates[s]
+	}
+	return "LookupState(" + strconv.Itoa(int(s)) + ")"
+}
+
+// GetFresh is like [Map.Get] but also tells apart keys not found from expired
+// entries, which [Map.Get] both reports as missing. In both cases, the zero
+// value of V is returned and expired entries are evicted like with Get.
+func (m *Map[K, V]) GetFresh(key K) (V, LookupState) {
+	_, i := m.find(key)
+	if i != 0 {
+		if it := &m.elms[i]; !it.expired() {
+			m.touch(i)
+			if m.stats != nil {
+				m.stats.Hits++
+			}
+			return it.value, Hit
+		}
+		m.evict(i)
+	}
+	if m.stats != nil {
+		m.stats.Misses++
+	}
+	var zero V
+	if i != 0 {
+		return zero, Expired
+	}
+	return zero, Miss
+}
+
+// PurgeExpired evicts all expired entries and returns the number of entries
+// evicted. It runs in O(n).
+func (m *Map[K, V]) PurgeExpired() int {
+	now := nanotime()
+	n := 0
+	for i := m.lru(); i != 0; {
+		it := &m.elms[i]
+		prev := it.prev
+		if it.expires != 0 && it.expires <= now {
+			m.evict(i)
+			n++
+		}
+		i = prev
+	}
+	return n
+}
+
+// StartJanitor starts a goroutine that calls [Map.PurgeExpired] at the given
+// interval. Since a Map is not safe for concurrent use, the janitor holds mu
+// while purging, and all other accesses to the Map must be guarded by the same
+// lock.
+//
+// The returned stop function stops the janitor and waits for it to return. It
+// is safe to call it more than once.
+//
+// StartJanitor panics if interval <= 0.
+func (m *Map[K, V]) StartJanitor(interval time.Duration, mu sync.Locker) (stop func()) {
+	if interval <= 0 {
+		panic("lru: StartJanitor: non-positive interval")
+	}
+	var (
+		once sync.Once
+		done = make(chan struct{})
+		wg   sync.WaitGroup
+		t    = time.NewTicker(interval)
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				mu.Lock()
+				m.PurgeExpired()
+				mu.Unlock()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		once.Do(func() { close(done) })
+		wg.Wait()
+	}
+}
@@ -0,0 +1,279 @@
+package lru_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/db47h/cache/v2/lru"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a manually advanced clock for TTL tests.
+type fakeClock struct {
+	now int64
+}
+
+func (c *fakeClock) nanotime() int64         { return c.now }
+func (c *fakeClock) advance(d time.Duration) { c.now += int64(d) }
+
+func newFakeClock(t *testing.T) *fakeClock {
+	c := &fakeClock{now: time.Now().UnixNano()}
+	t.Cleanup(lru.SetNanotime(c.nanotime))
+	return c
+}
+
+func TestMap_SortedKeys_expired(t *testing.T) {
+	clk := newFakeClock(t)
+	m := populate()
+	m.SetWithTTL("sun", 0, time.Second)
+	clk.advance(2 * time.Second)
+	keys := m.SortedKeys(func(a, b string) bool { return a > b })
+	require.Len(t, keys, len(td))
+	require.NotContains(t, keys, "sun")
+	require.Equal(t, "venus", keys[0])
+	// not deleted
+	require.Equal(t, len(td)+1, m.Len())
+}
+
+func TestMap_GetFresh(t *testing.T) {
+	clk := newFakeClock(t)
+	var reasons []lru.EvictReason
+	m := lru.NewMap[string, int](lru.WithStats(),
+		lru.WithOnEvictReason(func(_ string, _ int, r lru.EvictReason) { reasons = append(reasons, r) }))
+	m.SetWithTTL("sun", 1, time.Second)
+	m.SetWithTTL("moon", 2, time.Minute)
+	m.Set("earth", 3)
+
+	v, st := m.GetFresh("sun")
+	require.Equal(t, lru.Hit, st)
+	require.Equal(t, 1, v)
+	k, _ := m.MRU()
+	require.Equal(t, "sun", k)
+
+	clk.advance(2 * time.Second)
+	v, st = m.GetFresh("sun")
+	require.Equal(t, lru.Expired, st)
+	require.Zero(t, v)
+	require.Equal(t, 2, m.Len())
+	require.Equal(t, []lru.EvictReason{lru.ReasonExpired}, reasons)
+
+	_, st = m.GetFresh("sun")
+	require.Equal(t, lru.Miss, st)
+	v, st = m.GetFresh("moon")
+	require.Equal(t, lru.Hit, st)
+	require.Equal(t, 2, v)
+
+	st0 := m.Stats()
+	require.Equal(t, uint64(2), st0.Hits)
+	require.Equal(t, uint64(2), st0.Misses)
+	require.Equal(t, "expired", lru.Expired.String())
+}
+
+func TestWithExpiredFirst(t *testing.T) {
+	clk := newFakeClock(t)
+	var evicted []string
+	c := lru.New[string, int](len(td), lru.WithExpiredFirst(),
+		lru.WithOnEvict(func(k string, _ int) { evicted = append(evicted, k) }))
+	for _, d := range td {
+		c.Set(d.key, d.value)
+	}
+	c.SetWithTTL("mars", 4, time.Second)
+	c.SetWithTTL("venus", 2, time.Second)
+	c.SetWithTTL("earth", 3, time.Minute)
+	// not expired yet: plain LRU
+	c.Set("sun", 0)
+	require.Equal(t, []string{"mercury"}, evicted)
+
+	clk.advance(2 * time.Second)
+	// expired entries go first, least recently used first.
+	c.Set("moon", 0)
+	c.Set("pluto", 0)
+	require.Equal(t, []string{"mercury", "mars", "venus"}, evicted)
+	c.Set("ceres", 0)
+	require.Equal(t, []string{"mercury", "mars", "venus", "jupiter"}, evicted)
+
+	clk.advance(time.Minute)
+	require.Equal(t, 8, c.Len())
+	k, _ := c.DeleteLRU()
+	require.Equal(t, "earth", k)
+
+	// without the option, LRU order is kept.
+	m := lru.NewMap[string, int]()
+	m.SetWithTTL("a", 1, time.Second)
+	m.Set("b", 2)
+	m.SetWithTTL("c", 3, time.Second)
+	clk.advance(2 * time.Second)
+	m.Get("a")
+	m.Set("a", 1)
+	k, _ = m.DeleteLRU()
+	require.Equal(t, "b", k)
+}
+
+func TestMap_SetWithTTL(t *testing.T) {
+	clk := newFakeClock(t)
+	m := populate()
+	m.SetWithTTL("sun", 0, time.Second)
+	m.SetWithTTL("pluto", 9, time.Minute)
+
+	clk.advance(2 * time.Second)
+	_, ok := m.Get("sun")
+	require.False(t, ok)
+	// lazily deleted
+	require.Equal(t, len(td)+1, m.Len())
+	v, ok := m.Peek("pluto")
+	require.True(t, ok)
+	require.Equal(t, 9, v)
+
+	// no ttl on plain Set
+	clk.advance(time.Hour)
+	_, ok = m.Peek("mercury")
+	require.True(t, ok)
+	_, ok = m.Delete("pluto")
+	require.False(t, ok)
+	require.Equal(t, len(td), m.Len())
+
+	// replacing an expired entry is an insertion.
+	m.SetWithTTL("pluto", 9, time.Minute)
+	clk.advance(2 * time.Minute)
+	_, replaced := m.Set("pluto", 10)
+	require.False(t, replaced)
+	v, ok = m.Get("pluto")
+	require.True(t, ok)
+	require.Equal(t, 10, v)
+}
+
+func TestMap_Peek(t *testing.T) {
+	m := populate()
+	v, ok := m.Peek("mercury")
+	require.True(t, ok)
+	require.Equal(t, 1, v)
+	k, _ := m.LRU()
+	require.Equal(t, "mercury", k)
+	_, ok = m.Peek("pluto")
+	require.False(t, ok)
+}
+
+func TestMap_PurgeExpired(t *testing.T) {
+	clk := newFakeClock(t)
+	m := lru.NewMap[int, int](lru.WithDefaultTTL(time.Minute))
+	for i := range 100 {
+		if i%2 == 0 {
+			m.SetWithTTL(i, i, time.Hour)
+		} else {
+			m.Set(i, i)
+		}
+	}
+	require.Equal(t, 0, m.PurgeExpired())
+	clk.advance(time.Minute)
+	require.Equal(t, 50, m.PurgeExpired())
+	require.Equal(t, 50, m.Len())
+	for k := range m.Keys() {
+		require.Zero(t, k%2)
+	}
+}
+
+func TestMap_StartJanitor(t *testing.T) {
+	var mu sync.Mutex
+	clk := newFakeClock(t)
+	var evicted []int
+	m := lru.NewMap[int, int](lru.WithOnEvict(func(k, v int) { evicted = append(evicted, k) }))
+	mu.Lock()
+	for i := range 10 {
+		m.SetWithTTL(i, i, time.Duration(i+1)*time.Second)
+	}
+	mu.Unlock()
+
+	stop := m.StartJanitor(time.Millisecond, &mu)
+	defer stop()
+
+	mu.Lock()
+	clk.advance(5 * time.Second)
+	mu.Unlock()
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return m.Len() == 5
+	}, time.Second, time.Millisecond)
+
+	stop()
+	stop()
+	require.Equal(t, []int{0, 1, 2, 3, 4}, evicted)
+
+	require.PanicsWithValue(t, "lru: StartJanitor: non-positive interval", func() { m.StartJanitor(0, &mu) })
+	s := lru.NewSyncMap[int, int]()
+	require.Panics(t, func() { s.StartJanitor(-time.Second) })
+}
+
+func TestSyncMap_GetWithDefault_expired(t *testing.T) {
+	clk := newFakeClock(t)
+	var evicted []int
+	m := lru.NewSyncMap[string, int](lru.WithDefaultTTL(time.Hour),
+		lru.WithOnEvict(func(_ string, v int) { evicted = append(evicted, v) }))
+	n := 0
+	load := func(string) (int, error) {
+		n++
+		return n, nil
+	}
+	v, _ := m.GetWithDefault("file", load)
+	require.Equal(t, 1, v)
+	clk.advance(30 * time.Minute)
+	v, _ = m.GetWithDefault("file", load)
+	require.Equal(t, 1, v)
+
+	// stale entries are evicted and reloaded
+	clk.advance(time.Hour)
+	v, _ = m.GetWithDefault("file", load)
+	require.Equal(t, 2, v)
+	require.Equal(t, []int{1}, evicted)
+
+	// without a loader, stale entries are evicted and reported missing
+	clk.advance(2 * time.Hour)
+	_, ok := m.Get("file")
+	require.False(t, ok)
+	require.Equal(t, []int{1, 2}, evicted)
+	require.Zero(t, m.Len())
+}
+
+func TestSyncMap_WithNegativeTTL(t *testing.T) {
+	clk := newFakeClock(t)
+	m := lru.NewSyncMap[string, int](lru.WithNegativeTTL(time.Minute),
+		lru.WithOnEvict(func(string, int) { t.Fatal("unexpected eviction") }))
+	n := 0
+	load := func(k string) (int, error) {
+		n++
+		if k == "vulcan" {
+			return 0, fmt.Errorf("%s: %w", k, lru.ErrNotFound)
+		}
+		return len(k), nil
+	}
+	_, err := m.GetWithDefault("vulcan", load)
+	require.ErrorIs(t, err, lru.ErrNotFound)
+	require.Equal(t, 1, n)
+	require.Zero(t, m.Len())
+
+	// within the negative TTL, load is not called
+	clk.advance(30 * time.Second)
+	_, err = m.GetWithDefault("vulcan", load)
+	require.ErrorIs(t, err, lru.ErrNotFound)
+	require.Equal(t, 1, n)
+	v, err := m.GetWithDefault("pluto", load)
+	require.NoError(t, err)
+	require.Equal(t, 5, v)
+	require.Equal(t, 2, n)
+
+	// after it expires, it is
+	clk.advance(time.Minute)
+	_, err = m.GetWithDefault("vulcan", load)
+	require.ErrorIs(t, err, lru.ErrNotFound)
+	require.Equal(t, 3, n)
+
+	// no negative caching by default
+	m = lru.NewSyncMap[string, int]()
+	for range 2 {
+		_, err = m.GetWithDefault("vulcan", load)
+		require.ErrorIs(t, err, lru.ErrNotFound)
+	}
+	require.Equal(t, 5, n)
+}
@@ -0,0 +1,116 @@
+// Package swiss provides the low level primitives of the swiss table used by
+// [github.com/db47h/cache/v2/lru.Map], for use in custom open addressing hash
+// tables.
+//
+// A table using these primitives keeps one control byte per slot, in an array
+// of capacity+GroupSize-1 bytes where the first GroupSize-1 control bytes are
+// replicated at the end, so that a full group of GroupSize bytes can be loaded
+// from any slot. A control byte is either [Empty], [Deleted], or [H2] of the
+// hash of the key stored in the slot, which always has [SetMask] set.
+//
+// Hashes are split in two parts: [H1] selects the start of the probe sequence,
+// see [NewProbe], and [H2] is stored in the control byte. Lookups load the
+// control bytes of each group in the probe sequence with [NewBitset], and
+// compare keys only for the slots returned by [Bitset.MatchByte], stopping at
+// the first group where [Bitset.MatchEmpty] finds an empty slot.
+package swiss
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"unsafe"
+)
+
+const (
+	// GroupSize is the number of control bytes processed at once.
+	GroupSize = 8
+	// Empty is the control byte of slots that have never been used.
+	Empty = 0
+	// Deleted is the control byte of slots whose entry has been deleted. It
+	// is not 1 so that [Bitset.MatchEmpty] never yields false positives. For
+	// [MarkDeletedAsEmptyAndSetAsDeleted], it must be a power of two.
+	Deleted = 2
+	// SetMask is set in the control bytes of used slots, and only in these.
+	SetMask = 0x80
+
+	loBits = 0x0101010101010101
+	hiBits = 0x8080808080808080
+)
+
+// H1 returns the part of hash used to select the start of the probe
+// sequence.
+func H1(hash uint64) uint { return uint(hash >> 7) }
+
+// H2 returns the control byte for a key with the given hash.
+func H2(hash uint64) uint8 { return uint8(hash) | SetMask }
+
+// Bitset provides fast match operations over a group of [GroupSize] control
+// bytes. See https://graphics.stanford.edu/~seander/bithacks.html#ZeroInWord
+type Bitset uint64
+
+// NewBitset loads the group of [GroupSize] control bytes starting at c. These
+// must all be within the same array.
+func NewBitset(c *uint8) Bitset {
+	b := *(*[GroupSize]uint8)(unsafe.Pointer(c))
+	return Bitset(binary.LittleEndian.Uint64(b[:]))
+}
+
+// MatchNotSet matches slots that are either empty or deleted.
+func (s Bitset) MatchNotSet() Match { return (Match(s) & hiBits) ^ hiBits }
+
+// MatchSet matches slots that are set.
+func (s Bitset) MatchSet() Match { return Match(s) & hiBits }
+
+// MatchEmpty matches empty slots. This is the same operation as
+// [Bitset.MatchZero], but since control bytes are never 1, it does not yield
+// false positives.
+func (s Bitset) MatchEmpty() Match { return (Match(s) - loBits) & ^Match(s) & hiBits }
+
+// MatchZero returns a non zero Match if and only if s contains any zero byte.
+// Calling [Match.Next] on the result may yield false positives for any 0x01
+// byte following a zero byte.
+func (s Bitset) MatchZero() Match { return (Match(s) - loBits) & ^Match(s) & hiBits }
+
+// MatchByte returns a non zero Match if and only if s contains any byte equal
+// to b. Like with [Bitset.MatchZero], false positives are possible, so the
+// keys in the matching slots must be checked.
+func (s Bitset) MatchByte(b uint8) Match { return (s ^ (loBits * Bitset(b))).MatchZero() }
+
+// MatchDeleted matches deleted slots, provided that s only contains deleted
+// or empty slots, as is the case after [MarkDeletedAsEmptyAndSetAsDeleted].
+func (s Bitset) MatchDeleted() Match {
+	// do not even do s * (SetMask/Deleted) since Match.Next will work as
+	// intended with any non 0 byte.
+	return Match(s)
+}
+
+// MarkDeletedAsEmptyAndSetAsDeleted marks the deleted slots of the group
+// starting at c as empty and the set ones as deleted. This is the first step
+// of an in-place rehash.
+func MarkDeletedAsEmptyAndSetAsDeleted(c *uint8) {
+	s := *(*uint64)(unsafe.Pointer(c))
+	// clear deleted
+	s ^= Deleted
+	// mark set slots as deleted.
+	*(*uint64)(unsafe.Pointer(c)) = s & hiBits / (SetMask / Deleted)
+}
+
+// Match is the result of a match operation on a [Bitset].
+type Match uint64
+
+// Next returns the offset from the start of the group of the next match and
+// removes it from m. m must not be zero.
+func (m *Match) Next() int {
+	n := bits.TrailingZeros64(uint64(*m))
+	// shift by an unsigned value to avoid internal checks for negative shift amounts
+	*m &= ^(1 << uint(n))
+	return n >> 3
+}
+
+// First returns the offset of the first match. It does not update m and
+// returns [GroupSize] if m is zero.
+func (m Match) First() int { return bits.TrailingZeros64(uint64(m)) >> 3 }
+
+// FirstFromEnd returns the offset of the first match, counting from the end
+// of the group. It does not update m and returns [GroupSize] if m is zero.
+func (m Match) FirstFromEnd() int { return bits.LeadingZeros64(uint64(m)) >> 3 }
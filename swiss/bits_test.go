@@ -1,4 +1,4 @@
-package lru
+package swiss_test
 
 import (
 	"encoding/binary"
@@ -7,10 +7,11 @@ import (
 	"strconv"
 	"testing"
 
+	"github.com/db47h/cache/v2/swiss"
 	"github.com/stretchr/testify/require"
 )
 
-func Test_h1_h2(t *testing.T) {
+func TestH1(t *testing.T) {
 	// the goal of reduce range is to get a uniform distribution from X to N,
 	// so we'll test that instead of testing actual values.
 	ranges := []int{16, 1 << 12, 1 << 14}
@@ -20,7 +21,7 @@ func Test_h1_h2(t *testing.T) {
 			const mean = 2000
 			samples := n * mean
 			for range samples {
-				h1 := h1(rand.Uint64())
+				h1 := swiss.H1(rand.Uint64())
 				b := h1 & uint(len(buckets)-1)
 				buckets[b]++
 			}
@@ -37,35 +38,35 @@ func Test_h1_h2(t *testing.T) {
 	}
 }
 
-func Test_splitHash(t *testing.T) {
+func TestH1_H2(t *testing.T) {
 	const (
 		hash = 0x1122334455667ff8
 		eh1  = 0x1122334455667ff8 >> 7
 		eh2  = 0xf8
 	)
-	h1, h2 := h1(hash&math.MaxUint), h2(hash&math.MaxUint)
+	h1, h2 := swiss.H1(hash&math.MaxUint), swiss.H2(hash&math.MaxUint)
 	require.Equal(t, uint(eh1&math.MaxUint), h1)
 	require.Equal(t, uint8(eh2), h2)
 }
 
-func Test_bitset(t *testing.T) {
-	cs := make([]uint8, groupSize*2)
-	for i := range groupSize {
+func TestNewBitset(t *testing.T) {
+	cs := make([]uint8, swiss.GroupSize*2)
+	for i := range swiss.GroupSize {
 		cs[i] = uint8(i) + 1
 	}
-	for i := range groupSize - 1 {
-		cs[i+groupSize] = cs[i]
+	for i := range swiss.GroupSize - 1 {
+		cs[i+swiss.GroupSize] = cs[i]
 	}
-	cs[groupSize*2-1] = 0xFF
-	for i := range groupSize {
-		expected := bitset(binary.LittleEndian.Uint64(cs[i:]))
-		require.Equal(t, expected, newBitset(&cs[i]))
+	cs[swiss.GroupSize*2-1] = 0xFF
+	for i := range swiss.GroupSize {
+		expected := swiss.Bitset(binary.LittleEndian.Uint64(cs[i:]))
+		require.Equal(t, expected, swiss.NewBitset(&cs[i]))
 		// make sure we don't read past cs[size+GroupSize-2]
-		require.True(t, bitset(expected).matchByte(0xFF) == 0)
+		require.True(t, swiss.Bitset(expected).MatchByte(0xFF) == 0)
 	}
 }
 
-func Test_bitsset_matchNotSet(t *testing.T) {
+func TestBitset_MatchNotSet(t *testing.T) {
 	const sz = 32
 	cs := makeCtrl(32)
 	for range 1000 {
@@ -73,27 +74,27 @@ func Test_bitsset_matchNotSet(t *testing.T) {
 		// random start pos
 		pos := rand.IntN(sz)
 		// free a pair of slots
-		f1 := pos + rand.IntN(groupSize)
-		f2 := pos + rand.IntN(groupSize)
-		setCtrl(cs, f1, empty)
-		setCtrl(cs, f2, deleted)
+		f1 := pos + rand.IntN(swiss.GroupSize)
+		f2 := pos + rand.IntN(swiss.GroupSize)
+		setCtrl(cs, f1, swiss.Empty)
+		setCtrl(cs, f2, swiss.Deleted)
 		if f1 > f2 {
 			f1, f2 = f2, f1
 		}
-		m := newBitset(&cs[pos]).matchNotSet()
+		m := swiss.NewBitset(&cs[pos]).MatchNotSet()
 		require.True(t, m != 0)
-		p := m.next()
+		p := m.Next()
 		require.Equal(t, f1, pos+p, "F1")
-		require.True(t, cs[f1]&setMask == 0, "F1")
+		require.True(t, cs[f1]&swiss.SetMask == 0, "F1")
 		if f1 != f2 {
-			p = m.next()
+			p = m.Next()
 			require.Equal(t, f2, pos+p, "F2")
-			require.True(t, cs[f2]&setMask == 0, "F2")
+			require.True(t, cs[f2]&swiss.SetMask == 0, "F2")
 		}
 	}
 }
 
-func Test_bitset_matchByte(t *testing.T) {
+func TestBitset_MatchByte(t *testing.T) {
 	const sz = 32
 	cs := makeCtrl(32)
 	for range 1000 {
@@ -101,56 +102,61 @@ func Test_bitset_matchByte(t *testing.T) {
 		// random start pos
 		pos := rand.IntN(sz)
 		// free a pair of slots
-		f1 := pos + rand.IntN(groupSize)
-		f2 := pos + rand.IntN(groupSize)
-		v := uint8(rand.IntN(128-sz)+sz) | setMask
+		f1 := pos + rand.IntN(swiss.GroupSize)
+		f2 := pos + rand.IntN(swiss.GroupSize)
+		v := uint8(rand.IntN(128-sz)+sz) | swiss.SetMask
 		if f1 > f2 {
 			f1, f2 = f2, f1
 		}
 		setCtrl(cs, f1, v)
 		setCtrl(cs, f2, v)
-		s := newBitset(&cs[pos])
-		m := s.matchByte(v)
+		s := swiss.NewBitset(&cs[pos])
+		m := s.MatchByte(v)
 		require.True(t, m != 0)
-		p := m.next()
+		p := m.Next()
 		require.Equal(t, f1, pos+p, "F1")
 		require.Equal(t, v, cs[pos+p], "F1")
 		if f1 != f2 {
-			p := m.next()
+			p := m.Next()
 			require.Equal(t, f2, pos+p, "F2")
 			require.Equal(t, v, cs[pos+p], "F2")
 		}
 	}
 }
 
-func Test_bitset_markDeletedAsEmptyAndSetAsDeleted(t *testing.T) {
-	ctrl := []uint8{setMask | deleted, empty, deleted, deleted, setMask | empty, deleted, empty, setMask}
-	expect := []uint8{deleted, empty, empty, empty, deleted, empty, empty, deleted}
-	markDeletedAsEmptyAndSetAsDeleted(&ctrl[0])
+func TestMarkDeletedAsEmptyAndSetAsDeleted(t *testing.T) {
+	const (
+		e = swiss.Empty
+		d = swiss.Deleted
+		s = swiss.SetMask
+	)
+	ctrl := []uint8{s | d, e, d, d, s | e, d, e, s}
+	expect := []uint8{d, e, e, e, d, e, e, d}
+	swiss.MarkDeletedAsEmptyAndSetAsDeleted(&ctrl[0])
 	require.Equal(t, expect, ctrl)
 }
 
 func makeCtrl(sz int) []uint8 {
-	return make([]uint8, sz+groupSize-1)
+	return make([]uint8, sz+swiss.GroupSize-1)
 }
 
 func fillCtrl(b []uint8) {
-	sz := len(b) - groupSize + 1
+	sz := len(b) - swiss.GroupSize + 1
 	for i := range sz {
-		b[i] = setMask | uint8(i)
-		if i < groupSize-1 {
+		b[i] = swiss.SetMask | uint8(i)
+		if i < swiss.GroupSize-1 {
 			b[i+sz] = b[i]
 		}
 	}
 }
 
 func setCtrl(b []uint8, pos int, v uint8) int {
-	sz := len(b) - groupSize + 1
+	sz := len(b) - swiss.GroupSize + 1
 	if pos >= sz {
 		pos -= sz
 	}
 	b[pos] = v
-	if pos < groupSize-1 {
+	if pos < swiss.GroupSize-1 {
 		b[pos+sz] = b[pos]
 	}
 	return pos
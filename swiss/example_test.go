@@ -0,0 +1,75 @@
+package swiss_test
+
+import (
+	"fmt"
+
+	"github.com/db47h/cache/v2/swiss"
+)
+
+// set is a minimal fixed capacity set of uint64 built on swiss primitives. It
+// does not support deletions nor growing.
+type set struct {
+	ctrl []uint8
+	keys []uint64
+}
+
+func newSet(capacity int) *set {
+	return &set{
+		ctrl: make([]uint8, capacity+swiss.GroupSize-1),
+		keys: make([]uint64, capacity),
+	}
+}
+
+func (s *set) hash(k uint64) uint64 { return k * 0x9e3779b97f4a7c15 }
+
+// find returns the index of k and true if found, or the index of the slot
+// where k should be inserted and false.
+func (s *set) find(k uint64) (int, bool) {
+	h := s.hash(k)
+	for p := swiss.NewProbe(swiss.H1(h), len(s.keys)); ; p = p.Next() {
+		g := swiss.NewBitset(&s.ctrl[p.Offset()])
+		for m := g.MatchByte(swiss.H2(h)); m != 0; {
+			if i := p.Index(m.Next()); s.keys[i] == k {
+				return i, true
+			}
+		}
+		// without deletions, the first empty slot in the probe sequence is
+		// where lookups stop.
+		if m := g.MatchEmpty(); m != 0 {
+			return p.Index(m.First()), false
+		}
+	}
+}
+
+func (s *set) Add(k uint64) bool {
+	i, ok := s.find(k)
+	if ok {
+		return false
+	}
+	c := swiss.H2(s.hash(k))
+	s.ctrl[i] = c
+	if i < swiss.GroupSize-1 {
+		// replicate the first control bytes at the end of the array
+		s.ctrl[i+len(s.keys)] = c
+	}
+	s.keys[i] = k
+	return true
+}
+
+func (s *set) Has(k uint64) bool {
+	_, ok := s.find(k)
+	return ok
+}
+
+func Example() {
+	s := newSet(64)
+	n := 0
+	for i := range uint64(40) {
+		if s.Add(i % 20) {
+			n++
+		}
+	}
+	fmt.Println(n, s.Has(3), s.Has(42))
+	// Output:
+	// 20 true false
+}
@@ -0,0 +1,48 @@
+package swiss
+
+// Probe is a quadratic probe sequence over groups of [GroupSize] slots. Slot
+// indices are 0 based.
+type Probe struct {
+	offset int
+	acc    int
+	mask   int
+}
+
+// NewProbe returns the probe sequence for a key whose hash h1 is the result of
+// [H1], in a table of the given capacity.
+//
+// capacity must be a power of two, in which case masking the low bits of h1
+// maps it uniformly to [0, capacity) and the probe sequence visits all groups
+// before wrapping around.
+func NewProbe(h1 uint, capacity int) Probe {
+	mask := capacity - 1
+	return Probe{offset: int(h1) & mask, mask: mask}
+}
+
+// Next returns the next probe position using quadratic probing.
+func (p Probe) Next() Probe {
+	p.acc += GroupSize
+	p.offset += p.acc
+	p.offset &= p.mask
+	return p
+}
+
+// Prev returns the previous probe position. It must not be called on the
+// first position of the sequence.
+func (p Probe) Prev() Probe {
+	p.offset -= p.acc
+	p.offset &= p.mask
+	p.acc -= GroupSize
+	return p
+}
+
+// Offset returns the index of the first slot of the current group.
+func (p Probe) Offset() int { return p.offset }
+
+// Index returns the index of the slot at offset e from the start of the
+// current group, e.g. as returned by [Match.Next].
+func (p Probe) Index(e int) int { return (p.offset + e) & p.mask }
+
+// Dist returns the distance from the start of the current group to slot i,
+// wrapping around the end of the table.
+func (p Probe) Dist(i int) int { return (i - p.offset) & p.mask }
@@ -0,0 +1,44 @@
+package swiss_test
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/db47h/cache/v2/swiss"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbe(t *testing.T) {
+	for range 200 {
+		sz := 1 << (rand.N(10) + 5)
+		visited := make([]bool, sz)
+		p := swiss.NewProbe(uint(rand.Uint64()), sz)
+		// sz/GroupSize distinct offsets: all groups are visited.
+		for range sz / swiss.GroupSize {
+			require.False(t, visited[p.Offset()])
+			visited[p.Offset()] = true
+			require.Equal(t, p.Offset(), p.Index(0))
+			require.Equal(t, (p.Offset()+swiss.GroupSize-1)%sz, p.Index(swiss.GroupSize-1))
+			require.Equal(t, 3, p.Dist(p.Index(3)))
+			next := p.Next()
+			require.Equal(t, p, next.Prev())
+			p = next
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	cs := []uint8{0x81, swiss.Empty, swiss.Deleted, 0x81, swiss.Empty, 0x90, swiss.Deleted, 0x81}
+	s := swiss.NewBitset(&cs[0])
+	var got []int
+	for m := s.MatchByte(0x81); m != 0; {
+		got = append(got, m.Next())
+	}
+	require.Equal(t, []int{0, 3, 7}, got)
+	require.Equal(t, 1, s.MatchEmpty().First())
+	require.Equal(t, 3, s.MatchEmpty().FirstFromEnd())
+	require.Equal(t, 1, s.MatchNotSet().First())
+	require.Equal(t, 0, s.MatchSet().First())
+	require.Equal(t, swiss.GroupSize, s.MatchByte(0xFF).First())
+	require.Equal(t, swiss.GroupSize, s.MatchByte(0xFF).FirstFromEnd())
+}